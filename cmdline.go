@@ -0,0 +1,92 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// splitCommandLine splits a command line into arguments the way a POSIX
+// shell does, honoring single and double quotes and backslash escapes.
+// Expansions and other shell features are not supported.
+func splitCommandLine(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+	)
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case r == '\\':
+			i++
+			if i == len(runes) {
+				return nil, errors.New("trailing backslash")
+			}
+			current.WriteRune(runes[i])
+			inArg = true
+		case r == '\'':
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					closed = true
+					break
+				}
+				current.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated single quote")
+			}
+			inArg = true
+		case r == '"':
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				// within double quotes backslash only escapes these
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				current.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+			inArg = true
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmdline  string
+		expected []string
+		err      string
+	}{
+		{name: "Plain", cmdline: "  --port 8080\t--verbose ", expected: []string{"--port", "8080", "--verbose"}},
+		{name: "Empty", cmdline: "", expected: nil},
+		{name: "DoubleQuotes", cmdline: `--message "hello world"`, expected: []string{"--message", "hello world"}},
+		{name: "SingleQuotes", cmdline: `--message='hello "world"'`, expected: []string{"--message=hello \"world\""}},
+		{name: "EscapedQuote", cmdline: `--message "say \"hi\"" --name=O\'Brien`, expected: []string{"--message", `say "hi"`, "--name=O'Brien"}},
+		{name: "LiteralBackslash", cmdline: `"C:\path" 'a\b'`, expected: []string{`C:\path`, `a\b`}},
+		{name: "EscapedSpace", cmdline: `foo\ bar`, expected: []string{"foo bar"}},
+		{name: "EmptyQuoted", cmdline: `--name "" x`, expected: []string{"--name", "", "x"}},
+		{name: "Adjacent", cmdline: `--name="foo"'bar'baz`, expected: []string{"--name=foobarbaz"}},
+		{name: "UnterminatedDouble", cmdline: `--name "foo`, err: "unterminated double quote"},
+		{name: "UnterminatedSingle", cmdline: `--name 'foo`, err: "unterminated single quote"},
+		{name: "TrailingBackslash", cmdline: `--name foo\`, err: "trailing backslash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := splitCommandLine(tt.cmdline)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestParserParseString(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		var (
+			s string
+			i int
+		)
+		p := New()
+		p.String(&s, "message", "Test flag")
+		p.Int(&i, "count", "Test flag")
+
+		err := p.ParseString(`--message "it's \"quoted\"" --count=3`)
+		require.NoError(t, err)
+		assert.Equal(t, `it's "quoted"`, s)
+		assert.Equal(t, 3, i)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "message", "Test flag")
+
+		err := p.ParseString(`--message "foo`)
+		assert.EqualError(t, err, "splitting command line: unterminated double quote")
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"fmt"
+	"io"
+	"slices"
+)
+
+type command struct {
+	name        string
+	description string
+	parser      *Parser
+}
+
+// Command registers a subcommand with its own flags. The child parser
+// inherits the parent's options and gets the remaining arguments once the
+// command name is met among the parent's arguments.
+func (p *Parser) Command(name, description string) *Parser {
+	if p.lookupCommand(name) != nil {
+		panic(fmt.Sprintf("command %s is already registered", name))
+	}
+
+	opts := append(slices.Clone(p.options), WithAppName(p.getAppName()+" "+name))
+	child := New(opts...)
+	child.parent = p
+	// the environment is captured once by the root parser's New(), not
+	// by the options re-applied here
+	child.envSnapshot = p.envSnapshot
+
+	p.commands = append(p.commands, &command{
+		name:        name,
+		description: description,
+		parser:      child,
+	})
+
+	return child
+}
+
+// SelectedCommand returns the name of the subcommand selected by the parsed
+// arguments, or an empty string if none was.
+func (p *Parser) SelectedCommand() string {
+	if p.selectedCommand == nil {
+		return ""
+	}
+	return p.selectedCommand.name
+}
+
+// knowsEnvVar reports whether the envvar belongs to a flag of the parser
+// or any of its subcommands.
+func (p *Parser) knowsEnvVar(name string) bool {
+	for _, flag := range p.flags {
+		if flag.matchesEnvVar(name) {
+			return true
+		}
+	}
+
+	for _, cmd := range p.commands {
+		if cmd.parser.knowsEnvVar(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupGlobalFlag looks up a flag unknown to the subcommand among the flags
+// of its parent commands, returning the parser owning it. Flags of the parent
+// commands are global ones, i.e. they could be set after the subcommand name
+// as well.
+func (p *Parser) lookupGlobalFlag(name string) (*Parser, flag) {
+	for parent := p.parent; parent != nil; parent = parent.parent {
+		if f, err := parent.lookupFlag(name); err == nil {
+			return parent, f
+		}
+	}
+	return nil, nil
+}
+
+// lookupArgFlag is lookupFlag falling back to the global flags.
+func (p *Parser) lookupArgFlag(name string) (flag, error) {
+	f, err := p.lookupFlag(name)
+	if err != nil {
+		if _, global := p.lookupGlobalFlag(name); global != nil {
+			return global, nil
+		}
+	}
+	return f, err
+}
+
+// lookupShortFlag looks up a short flag of the parser, falling back to the
+// global short flags unless their long names are taken by the subcommand.
+func (p *Parser) lookupShortFlag(r rune) flag {
+	if f := p.shortIndex[r]; f != nil {
+		return f
+	}
+
+	for parent := p.parent; parent != nil; parent = parent.parent {
+		if f := parent.shortIndex[r]; f != nil {
+			if _, shadowed := p.flagIndex[f.getName()]; shadowed {
+				return nil
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *Parser) lookupCommand(name string) *command {
+	for _, cmd := range p.commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// unknownCommand builds the error for an argument which is not a command
+// name, suggesting the closest command if any.
+func (p *Parser) unknownCommand(name string) error {
+	err := &UnknownCommandError{Name: name}
+
+	best := len(name)/3 + 2
+	for _, cmd := range p.commands {
+		err.Available = append(err.Available, cmd.name)
+		if d := editDistance(name, cmd.name); d < best {
+			best, err.Suggestion = d, cmd.name
+		}
+	}
+
+	return err
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// helpParser returns the parser whose help was requested, descending into
+// the selected subcommands.
+func (p *Parser) helpParser() *Parser {
+	if !p.helpCalled && p.selectedCommand != nil {
+		return p.selectedCommand.parser.helpParser()
+	}
+	return p
+}
+
+func (p *Parser) printCommandsHelp(w io.Writer) {
+	if len(p.commands) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "\n")
+	fmt.Fprintln(w, "Commands:")
+
+	tw := p.newHelpTabWriter(w)
+	for _, cmd := range p.commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,257 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserCommand(t *testing.T) {
+	type app struct {
+		parser  *Parser
+		verbose bool
+		port    int
+		dir     string
+	}
+
+	newApp := func(opts ...Option) *app {
+		a := &app{}
+		a.parser = New(append([]Option{WithAppName("myapp")}, opts...)...)
+		a.parser.Bool(&a.verbose, "verbose", "Verbose output")
+
+		serve := a.parser.Command("serve", "Start the server")
+		serve.Int(&a.port, "port", "Port to listen on").Default(80)
+
+		migrate := a.parser.Command("migrate", "Run migrations")
+		migrate.String(&a.dir, "dir", "Migrations directory").Required()
+
+		return a
+	}
+
+	t.Run("Dispatch", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"--verbose", "serve", "--port=8080"})
+		require.NoError(t, err)
+		assert.Equal(t, "serve", a.parser.SelectedCommand())
+		assert.True(t, a.verbose)
+		assert.Equal(t, 8080, a.port)
+		assert.Empty(t, a.dir)
+	})
+
+	t.Run("NoCommand", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"--verbose"})
+		require.NoError(t, err)
+		assert.Equal(t, "", a.parser.SelectedCommand())
+	})
+
+	t.Run("SubcommandError", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"migrate"})
+		var missingFlagErr *MissingFlagError
+		require.ErrorAs(t, err, &missingFlagErr)
+		assert.Equal(t, "dir", missingFlagErr.Name)
+	})
+
+	t.Run("GlobalFlagAfterCommand", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"serve", "--verbose", "--port=8080"})
+		require.NoError(t, err)
+		assert.True(t, a.verbose)
+		assert.Equal(t, 8080, a.port)
+	})
+
+	t.Run("GlobalFlagPrecedence", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"--verbose", "serve", "--verbose=false"})
+		require.NoError(t, err)
+		assert.False(t, a.verbose)
+	})
+
+	t.Run("GlobalFlagStrict", func(t *testing.T) {
+		a := newApp(WithStrictMode())
+
+		err := a.parser.ParseArgs([]string{"--verbose", "serve", "--verbose"})
+		assert.EqualError(t, err, "--verbose is set more than once")
+	})
+
+	t.Run("GlobalShortFlag", func(t *testing.T) {
+		var (
+			verbose bool
+			name    string
+		)
+
+		p := New()
+		p.Bool(&verbose, "verbose", "Verbose output").Short('v')
+		p.String(&name, "name", "Name").Short('n')
+		p.Command("greet", "Greet someone")
+
+		err := p.ParseArgs([]string{"greet", "-vn", "world"})
+		require.NoError(t, err)
+		assert.True(t, verbose)
+		assert.Equal(t, "world", name)
+	})
+
+	t.Run("GlobalFlagShadowed", func(t *testing.T) {
+		var rootName, childName string
+
+		p := New()
+		p.String(&rootName, "name", "Name").Short('n')
+		child := p.Command("greet", "Greet someone")
+		child.String(&childName, "name", "Name")
+
+		err := p.ParseArgs([]string{"greet", "--name=world"})
+		require.NoError(t, err)
+		assert.Empty(t, rootName)
+		assert.Equal(t, "world", childName)
+
+		err = p.ParseArgs([]string{"greet", "-n", "world"})
+		var unknownFlagErr *UnknownFlagError
+		assert.ErrorAs(t, err, &unknownFlagErr)
+	})
+
+	t.Run("UnknownCommandNearMiss", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"serv", "--port=8080"})
+		var unknownCmdErr *UnknownCommandError
+		require.ErrorAs(t, err, &unknownCmdErr)
+		assert.EqualError(t, err, `unknown command "serv", did you mean "serve"?`)
+		assert.Equal(t, 0, a.port)
+
+		err = a.parser.ParseArgs([]string{"migrte"})
+		assert.EqualError(t, err, `unknown command "migrte", did you mean "migrate"?`)
+	})
+
+	t.Run("UnknownCommandFarMiss", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"deploy"})
+		var unknownCmdErr *UnknownCommandError
+		require.ErrorAs(t, err, &unknownCmdErr)
+		assert.Empty(t, unknownCmdErr.Suggestion)
+		assert.EqualError(t, err, `unknown command "deploy", available: serve, migrate`)
+	})
+
+	t.Run("StrictEnvShared", func(t *testing.T) {
+		t.Setenv("APP_VERBOSE", "true")
+		t.Setenv("APP_PORT", "8080")
+
+		a := newApp(WithEnvVarPrefix("APP_"), WithStrictEnv())
+
+		err := a.parser.ParseArgs([]string{"serve"})
+		require.NoError(t, err)
+		assert.True(t, a.verbose)
+		assert.Equal(t, 8080, a.port)
+	})
+
+	t.Run("ParentCheckFailure", func(t *testing.T) {
+		var (
+			name   string
+			port   = 1
+			called bool
+		)
+
+		p := New(WithAtomicParse())
+		p.String(&name, "name", "Name").Required()
+		serve := p.Command("serve", "Start the server")
+		serve.Int(&port, "port", "Port to listen on")
+		serve.OnParsed(func(*Parser) { called = true })
+
+		err := p.ParseArgs([]string{"serve", "--port=80"})
+		var missingFlagErr *MissingFlagError
+		require.ErrorAs(t, err, &missingFlagErr)
+		assert.Equal(t, "name", missingFlagErr.Name)
+		assert.False(t, called)
+		assert.Equal(t, 1, port)
+		assert.Panics(t, serve.MustBeParsed)
+
+		err = p.ParseArgs([]string{"--name=foo", "serve", "--port=80"})
+		require.NoError(t, err)
+		assert.True(t, called)
+		assert.Equal(t, 80, port)
+		assert.NotPanics(t, serve.MustBeParsed)
+	})
+
+	t.Run("EchoArgsOnce", func(t *testing.T) {
+		a := newApp(WithEchoArgsOnError())
+
+		err := a.parser.ParseArgs([]string{"serve", "--port=x"})
+		assert.EqualError(t, err, "invalid value for --port: strconv.Atoi: parsing \"x\": invalid syntax\n"+
+			"provided args: serve --port=x")
+	})
+
+	t.Run("EnvSnapshotShared", func(t *testing.T) {
+		t.Setenv("PORT", "8080")
+
+		p := New(WithEnvSnapshot())
+		t.Setenv("PORT", "9090")
+
+		var port int
+		p.Command("serve", "Start the server").Int(&port, "port", "Port to listen on")
+
+		err := p.ParseArgs([]string{"serve"})
+		require.NoError(t, err)
+		assert.Equal(t, 8080, port)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		a := newApp()
+
+		buf := bytes.NewBuffer(nil)
+		a.parser.printHelp(buf)
+
+		assert.Equal(t, "Usage: myapp [--help] [--verbose] <command>\n\n"+
+			"Flags:\n"+
+			"  --help     Show help message\n"+
+			"  --verbose  Verbose output [$VERBOSE]\n"+
+			"\n"+
+			"Commands:\n"+
+			"  serve    Start the server\n"+
+			"  migrate  Run migrations\n", buf.String())
+	})
+
+	t.Run("SubcommandHelp", func(t *testing.T) {
+		a := newApp()
+
+		err := a.parser.ParseArgs([]string{"serve", "--help"})
+		assert.ErrorIs(t, err, ErrHelp)
+
+		buf := bytes.NewBuffer(nil)
+		a.parser.WriteHelp(buf)
+
+		assert.Equal(t, "Usage: myapp serve [--help] [--port=INT]\n\n"+
+			"Flags:\n"+
+			"  --help      Show help message\n"+
+			"  --port=INT  Port to listen on (default: 80) [$PORT]\n", buf.String())
+	})
+
+	t.Run("DuplicatePanic", func(t *testing.T) {
+		p := New()
+		p.Command("serve", "Start the server")
+		assert.Panics(t, func() {
+			p.Command("serve", "Start the server")
+		})
+	})
+}
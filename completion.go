@@ -0,0 +1,80 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func (p *Parser) GenerateCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "fish":
+		p.generateFishCompletion(w)
+	case "powershell":
+		p.generatePowerShellCompletion(w)
+	default:
+		return fmt.Errorf("unsupported shell: %s", shell)
+	}
+
+	return nil
+}
+
+func (p *Parser) generateFishCompletion(w io.Writer) {
+	appName := p.getAppName()
+
+	for _, flag := range p.visibleFlags() {
+		fmt.Fprintf(w, "complete -c %s -l %s", appName, flag.getName())
+		if !flag.isBoolFlag() {
+			fmt.Fprint(w, " -r")
+		}
+		if helpMessage := flag.getHelpMessage(); helpMessage != "" {
+			fmt.Fprintf(w, " -d %s", fishQuote(helpMessage))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+func (p *Parser) generatePowerShellCompletion(w io.Writer) {
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", powerShellQuote(p.getAppName()))
+	fmt.Fprintln(w, "    param($wordToComplete, $commandAst, $cursorPosition)")
+	fmt.Fprintln(w, "    $flags = @(")
+	for _, flag := range p.visibleFlags() {
+		name := "--" + flag.getName()
+
+		tooltip := flag.getHelpMessage()
+		if tooltip == "" {
+			tooltip = name
+		}
+
+		fmt.Fprintf(w, "        @{ Name = %s; Tooltip = %s }\n", powerShellQuote(name), powerShellQuote(tooltip))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    $flags | Where-Object { $_.Name -like \"$wordToComplete*\" } | ForEach-Object {")
+	fmt.Fprintln(w, "        [System.Management.Automation.CompletionResult]::new($_.Name, $_.Name, 'ParameterName', $_.Tooltip)")
+	fmt.Fprintln(w, "    }")
+	fmt.Fprintln(w, "}")
+}
+
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
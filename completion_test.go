@@ -0,0 +1,69 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserGenerateCompletion(t *testing.T) {
+	t.Run("Fish", func(t *testing.T) {
+		var (
+			b bool
+			i int
+		)
+
+		p := New(WithAppName("test-app"))
+		p.Bool(&b, "test-bool-flag", "Test bool flag")
+		p.Int(&i, "test-int-flag", "Test int flag, it's an int")
+
+		buf := bytes.NewBuffer(nil)
+		err := p.GenerateCompletion(buf, "fish")
+		require.NoError(t, err)
+
+		const expected = "complete -c test-app -l help -d 'Show help message'\n" +
+			"complete -c test-app -l test-bool-flag -d 'Test bool flag'\n" +
+			"complete -c test-app -l test-int-flag -r -d 'Test int flag, it\\'s an int'\n"
+
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("PowerShell", func(t *testing.T) {
+		var i int
+
+		p := New(WithAppName("test-app"))
+		p.Int(&i, "test-int-flag", "Test int flag, it's an int")
+
+		buf := bytes.NewBuffer(nil)
+		err := p.GenerateCompletion(buf, "powershell")
+		require.NoError(t, err)
+
+		assert.Contains(t, buf.String(), "Register-ArgumentCompleter -Native -CommandName 'test-app' -ScriptBlock {\n")
+		assert.Contains(t, buf.String(), "@{ Name = '--help'; Tooltip = 'Show help message' }\n")
+		assert.Contains(t, buf.String(), "@{ Name = '--test-int-flag'; Tooltip = 'Test int flag, it''s an int' }\n")
+	})
+
+	t.Run("UnknownShell", func(t *testing.T) {
+		p := New()
+
+		buf := bytes.NewBuffer(nil)
+		err := p.GenerateCompletion(buf, "tcsh")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type layeredValue struct {
+	value string
+	layer int
+}
+
+// LoadLayered reads JSON objects of flag names to values, later layers
+// overriding earlier ones. The resulting values take precedence over
+// defaults, but not over envvars or the command line.
+func (p *Parser) LoadLayered(readers ...io.Reader) error {
+	defer p.recordTiming("layered", time.Now())
+
+	values := make(map[string]layeredValue)
+
+	for i, r := range readers {
+		var layer map[string]json.RawMessage
+		if err := json.NewDecoder(r).Decode(&layer); err != nil {
+			return fmt.Errorf("layer %d: %w", i, err)
+		}
+
+		for name, raw := range layer {
+			f, err := p.lookupFlag(name)
+			if err != nil {
+				return fmt.Errorf("layer %d: %w", i, err)
+			}
+
+			values[f.getName()] = layeredValue{value: layerValueString(raw), layer: i}
+		}
+	}
+
+	p.layeredValues = values
+	return nil
+}
+
+func (p *Parser) setValueFromLayers(f flag) error {
+	v, ok := p.layeredValues[f.getName()]
+	if !ok {
+		return nil
+	}
+
+	if err := f.parseValue(v.value, sourceConfig); err != nil {
+		return fmt.Errorf("layer %d: %w", v.layer, err)
+	}
+
+	return nil
+}
+
+// layerValueString converts a JSON value to the form accepted by flag
+// parsers: strings are unquoted, string arrays are joined with commas,
+// anything else (numbers, bools, objects) is kept verbatim.
+func layerValueString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, ",")
+	}
+
+	return string(raw)
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserLoadLayered(t *testing.T) {
+	const (
+		base  = `{"host": "base-host", "port": 8080, "tags": ["a", "b"], "timeout": "10s"}`
+		env   = `{"host": "env-host", "timeout": "20s"}`
+		local = `{"port": 9090, "host": "local-host"}`
+	)
+
+	newParser := func(host *string, port *int, tags *[]string, timeout *time.Duration) *Parser {
+		p := New()
+		p.String(host, "host", "Test flag").Default("default-host")
+		p.Int(port, "port", "Test flag")
+		p.StringSlice(tags, "tags", "Test flag")
+		p.Duration(timeout, "timeout", "Test flag")
+		return p
+	}
+
+	t.Run("Layers", func(t *testing.T) {
+		var (
+			host    string
+			port    int
+			tags    []string
+			timeout time.Duration
+		)
+		p := newParser(&host, &port, &tags, &timeout)
+
+		err := p.LoadLayered(strings.NewReader(base), strings.NewReader(env), strings.NewReader(local))
+		require.NoError(t, err)
+
+		err = p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "local-host", host)
+		assert.Equal(t, 9090, port)
+		assert.Equal(t, []string{"a", "b"}, tags)
+		assert.Equal(t, 20*time.Second, timeout)
+	})
+
+	t.Run("EnvAndArgsOverride", func(t *testing.T) {
+		t.Setenv("HOST", "envvar-host")
+
+		var (
+			host    string
+			port    int
+			tags    []string
+			timeout time.Duration
+		)
+		p := newParser(&host, &port, &tags, &timeout)
+
+		err := p.LoadLayered(strings.NewReader(base), strings.NewReader(local))
+		require.NoError(t, err)
+
+		err = p.ParseArgs([]string{"--port=7070"})
+		require.NoError(t, err)
+		assert.Equal(t, "envvar-host", host)
+		assert.Equal(t, 7070, port)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		var (
+			host    string
+			port    int
+			tags    []string
+			timeout time.Duration
+		)
+		p := newParser(&host, &port, &tags, &timeout)
+
+		err := p.LoadLayered(strings.NewReader(base), strings.NewReader(`{"unknown": 1}`))
+		assert.EqualError(t, err, "layer 1: unknown flag: --unknown")
+
+		err = p.LoadLayered(strings.NewReader(base), strings.NewReader(`{"port": "abc"}`))
+		require.NoError(t, err)
+
+		err = p.ParseArgs(nil)
+		assert.EqualError(t, err, `layer 1: invalid value for --port: strconv.Atoi: parsing "abc": invalid syntax`)
+	})
+}
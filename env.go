@@ -0,0 +1,218 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+func (p *Parser) LoadDotEnv(r io.Reader) error {
+	defer p.recordTiming("dotenv", time.Now())
+
+	if p.dotEnv == nil {
+		p.dotEnv = make(map[string]string)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		name, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: missing '=' in %q", lineNum, line)
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("line %d: empty variable name", lineNum)
+		}
+
+		val, err := p.parseDotEnvValue(strings.TrimSpace(val))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		p.dotEnv[name] = val
+	}
+
+	return scanner.Err()
+}
+
+func (p *Parser) parseDotEnvValue(val string) (string, error) {
+	switch {
+	case strings.HasPrefix(val, "'"):
+		// single-quoted values are taken literally
+		if len(val) < 2 || !strings.HasSuffix(val, "'") {
+			return "", errors.New("unterminated single-quoted value")
+		}
+		return val[1 : len(val)-1], nil
+	case strings.HasPrefix(val, `"`):
+		if len(val) < 2 || !strings.HasSuffix(val, `"`) {
+			return "", errors.New("unterminated double-quoted value")
+		}
+		val = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(val[1 : len(val)-1])
+	}
+
+	return p.expandDotEnvValue(val)
+}
+
+// expandDotEnvValue expands ${VAR} references using the previously loaded
+// keys first and the process environment next. $$ stands for a literal $.
+func (p *Parser) expandDotEnvValue(val string) (string, error) {
+	b := &strings.Builder{}
+
+	for {
+		idx := strings.IndexByte(val, '$')
+		if idx == -1 || idx == len(val)-1 {
+			b.WriteString(val)
+			return b.String(), nil
+		}
+
+		b.WriteString(val[:idx])
+		val = val[idx+1:]
+
+		switch val[0] {
+		case '$':
+			b.WriteByte('$')
+			val = val[1:]
+		case '{':
+			end := strings.IndexByte(val, '}')
+			if end == -1 {
+				return "", errors.New("unterminated variable reference")
+			}
+
+			name := val[1:end]
+			if v, ok := p.dotEnv[name]; ok {
+				b.WriteString(v)
+			} else if v, ok := p.lookupProcessEnv(name); ok {
+				b.WriteString(v)
+			}
+			val = val[end+1:]
+		default:
+			b.WriteByte('$')
+		}
+	}
+}
+
+func (p *Parser) environ() []string {
+	var names []string
+
+	if p.envSnapshot != nil {
+		for name := range p.envSnapshot {
+			names = append(names, name)
+		}
+	} else {
+		for _, kv := range os.Environ() {
+			if name, _, ok := strings.Cut(kv, "="); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for name := range p.dotEnv {
+		if _, ok := p.lookupProcessEnv(name); !ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func (p *Parser) checkUnknownEnv() []error {
+	if !p.strictEnv || p.envVarPrefix == "" {
+		return nil
+	}
+
+	names := p.environ()
+	slices.Sort(names)
+
+	var checkErrs []error
+	for _, name := range names {
+		if !strings.HasPrefix(name, p.envVarPrefix) || p.isKnownEnvVar(name) || p.isIgnoredEnvVar(name) {
+			continue
+		}
+
+		checkErrs = append(checkErrs, fmt.Errorf("unknown environment variable: $%s", name))
+	}
+
+	return checkErrs
+}
+
+func (p *Parser) isKnownEnvVar(name string) bool {
+	// envvars of the whole command tree are known, as the environment is
+	// shared between commands
+	root := p
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	return root.knowsEnvVar(name)
+}
+
+func (p *Parser) isIgnoredEnvVar(name string) bool {
+	for _, pattern := range p.ignoredEnvVars {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Parser) WriteEnvTemplate(w io.Writer) {
+	first := true
+	for _, flag := range p.sortedFlags() {
+		envVarName := flag.getEnvVarName()
+		if envVarName == "" {
+			continue
+		}
+
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+
+		fmt.Fprintf(w, "# --%s", flag.getName())
+		if helpMessage := flag.getHelpMessage(); helpMessage != "" {
+			fmt.Fprintf(w, ": %s", helpMessage)
+		}
+
+		defaultValue, hasDefault := flag.getDefaultValueString()
+		switch {
+		case flag.isRequired():
+			fmt.Fprintf(w, " %s\n", p.requiredLabel)
+			fmt.Fprintf(w, "%s=\n", envVarName)
+		case hasDefault:
+			fmt.Fprintf(w, " "+p.defaultLabelFormat+"\n", defaultValue)
+			fmt.Fprintf(w, "#%s=%s\n", envVarName, defaultValue)
+		default:
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "#%s=\n", envVarName)
+		}
+	}
+}
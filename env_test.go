@@ -0,0 +1,341 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserWriteEnvTemplate(t *testing.T) {
+	var (
+		b bool
+		i int
+		s string
+	)
+
+	p := New(WithEnvVarPrefix("APP_"))
+	p.Bool(&b, "test-bool-flag", "Test bool flag")
+	p.Int(&i, "test-int-flag", "Test int flag").Required()
+	p.String(&s, "test-string-flag", "Test string flag").Default("foo")
+
+	buf := bytes.NewBuffer(nil)
+	p.WriteEnvTemplate(buf)
+
+	const expected = "# --test-bool-flag: Test bool flag\n" +
+		"#APP_TEST_BOOL_FLAG=\n" +
+		"\n" +
+		"# --test-int-flag: Test int flag (required)\n" +
+		"APP_TEST_INT_FLAG=\n" +
+		"\n" +
+		"# --test-string-flag: Test string flag (default: foo)\n" +
+		"#APP_TEST_STRING_FLAG=foo\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestParserStrictEnv(t *testing.T) {
+	t.Run("KnownVars", func(t *testing.T) {
+		t.Setenv("TESTAPP_PORT", "8080")
+		t.Setenv("TESTAPP_ITEMS_0", "foo")
+
+		var (
+			i     int
+			items []string
+		)
+		p := New(WithEnvVarPrefix("TESTAPP_"), WithStrictEnv())
+		p.Int(&i, "port", "Test flag")
+		p.StringSlice(&items, "items", "Test flag").EnvIndexed()
+
+		err := p.ParseArgs(nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("UnknownVar", func(t *testing.T) {
+		t.Setenv("TESTAPP_PORTT", "8080")
+
+		var i int
+		p := New(WithEnvVarPrefix("TESTAPP_"), WithStrictEnv())
+		p.Int(&i, "port", "Test flag")
+
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "unknown environment variable: $TESTAPP_PORTT")
+	})
+
+	t.Run("IgnoredVars", func(t *testing.T) {
+		t.Setenv("TESTAPP_POD_NAME", "foo")
+		t.Setenv("TESTAPP_SERVICE_HOST", "foo")
+		t.Setenv("TESTAPP_PROT", "8080")
+
+		var i int
+		p := New(
+			WithEnvVarPrefix("TESTAPP_"),
+			WithStrictEnv(),
+			WithIgnoredEnvVars("TESTAPP_POD_NAME", "TESTAPP_SERVICE_*"),
+		)
+		p.Int(&i, "port", "Test flag")
+
+		err := p.ParseArgs(nil)
+		require.Error(t, err)
+		assert.EqualError(t, err, "unknown environment variable: $TESTAPP_PROT")
+	})
+}
+
+func TestParserLoadDotEnv(t *testing.T) {
+	t.Run("Values", func(t *testing.T) {
+		const dotEnv = `
+# comment
+HOST=localhost
+export PORT = 8080
+SINGLE='${HOST} $$'
+DOUBLE="line1\nline2"
+`
+
+		p := New()
+		err := p.LoadDotEnv(strings.NewReader(dotEnv))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"HOST":   "localhost",
+			"PORT":   "8080",
+			"SINGLE": "${HOST} $$",
+			"DOUBLE": "line1\nline2",
+		}, p.dotEnv)
+	})
+
+	t.Run("IntraFileReference", func(t *testing.T) {
+		const dotEnv = "HOST=localhost\n" +
+			"PORT=8080\n" +
+			"URL=http://${HOST}:${PORT}/$$path\n"
+
+		var s string
+		p := New(WithoutAutoEnv())
+		p.String(&s, "url", "Test flag").Env("URL")
+
+		err := p.LoadDotEnv(strings.NewReader(dotEnv))
+		require.NoError(t, err)
+
+		err = p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/$path", s)
+	})
+
+	t.Run("EnvFallback", func(t *testing.T) {
+		t.Setenv("TEST_DOTENV_HOST", "example.com")
+
+		p := New()
+		err := p.LoadDotEnv(strings.NewReader("URL=http://${TEST_DOTENV_HOST}/${TEST_DOTENV_UNSET}\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://example.com/", p.dotEnv["URL"])
+	})
+
+	t.Run("ProcessEnvWins", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "from-env")
+
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag")
+
+		err := p.LoadDotEnv(strings.NewReader("TEST_FLAG=from-dotenv\n"))
+		require.NoError(t, err)
+
+		err = p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "from-env", s)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		p := New()
+		err := p.LoadDotEnv(strings.NewReader("FOO=bar\nBAZ\n"))
+		assert.EqualError(t, err, `line 2: missing '=' in "BAZ"`)
+	})
+}
+
+func TestParserEnvVarPrefixFormatter(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "db.internal")
+
+	screamingSnakeCase := func(s string) string {
+		b := &strings.Builder{}
+		for i, r := range s {
+			if unicode.IsUpper(r) && i > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		return b.String()
+	}
+
+	var s string
+	p := New(
+		WithEnvVarPrefix("App."),
+		WithEnvVarPrefixFormatter(func(s string) string {
+			return strings.ToUpper(strings.TrimSuffix(s, ".")) + "_"
+		}),
+		WithEnvVarFormatter(screamingSnakeCase),
+	)
+	f := p.String(&s, "dbHost", "Test flag")
+	assert.Equal(t, "APP_DB_HOST", f.getEnvVarName())
+
+	err := p.ParseArgs(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "db.internal", s)
+}
+
+func TestParserStrictBoolEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "true", valid: true},
+		{value: "false", valid: true},
+		{value: "TRUE", valid: false},
+		{value: "1", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TEST_FLAG", tt.value)
+
+			var b bool
+			p := New(WithStrictBoolEnv())
+			p.Bool(&b, "test-flag", "Test flag")
+
+			err := p.ParseArgs(nil)
+			if !tt.valid {
+				assert.EqualError(t, err, fmt.Sprintf("invalid value for --test-flag from $TEST_FLAG: %q is not exactly true or false", tt.value))
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("ArgsPermissive", func(t *testing.T) {
+		var b bool
+		p := New(WithStrictBoolEnv())
+		p.Bool(&b, "test-flag", "Test flag")
+
+		err := p.ParseArgs([]string{"--test-flag=1"})
+		require.NoError(t, err)
+		assert.True(t, b)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "1")
+
+		var b bool
+		p := New()
+		p.Bool(&b, "test-flag", "Test flag")
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.True(t, b)
+	})
+}
+
+func TestParserNumericEnvErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(p *Parser)
+		value    string
+		err      string
+	}{
+		{
+			name:     "IntFractional",
+			register: func(p *Parser) { p.Int(new(int), "count", "Test flag") },
+			value:    "3.5",
+			err:      `invalid value for --count from $APP_COUNT: strconv.Atoi: parsing "3.5": invalid syntax`,
+		},
+		{
+			name:     "IntOverflow",
+			register: func(p *Parser) { p.Int(new(int), "count", "Test flag") },
+			value:    "99999999999999999999",
+			err:      `invalid value for --count from $APP_COUNT: strconv.Atoi: parsing "99999999999999999999": value out of range`,
+		},
+		{
+			name:     "IntGarbage",
+			register: func(p *Parser) { p.Int(new(int), "count", "Test flag") },
+			value:    "ten",
+			err:      `invalid value for --count from $APP_COUNT: strconv.Atoi: parsing "ten": invalid syntax`,
+		},
+		{
+			name:     "FloatOverflow",
+			register: func(p *Parser) { p.Float(new(float64), 64, "ratio", "Test flag") },
+			value:    "1e999",
+			err:      `invalid value for --ratio from $APP_RATIO: strconv.ParseFloat: parsing "1e999": value out of range`,
+		},
+		{
+			name:     "FloatInf",
+			register: func(p *Parser) { p.Float(new(float64), 64, "ratio", "Test flag").FiniteOnly() },
+			value:    "Inf",
+			err:      `invalid value for --ratio from $APP_RATIO: must be a finite number`,
+		},
+		{
+			name:     "FloatNaN",
+			register: func(p *Parser) { p.Float(new(float64), 64, "ratio", "Test flag").FiniteOnly() },
+			value:    "NaN",
+			err:      `invalid value for --ratio from $APP_RATIO: must be a finite number`,
+		},
+		{
+			name:     "FloatGarbage",
+			register: func(p *Parser) { p.Float(new(float64), 64, "ratio", "Test flag") },
+			value:    "1.5x",
+			err:      `invalid value for --ratio from $APP_RATIO: strconv.ParseFloat: parsing "1.5x": invalid syntax`,
+		},
+		{
+			name:     "DurationUnitless",
+			register: func(p *Parser) { p.Duration(new(time.Duration), "timeout", "Test flag") },
+			value:    "30",
+			err:      `invalid value for --timeout from $APP_TIMEOUT: time: missing unit in duration "30"`,
+		},
+		{
+			name:     "DurationOverflow",
+			register: func(p *Parser) { p.Duration(new(time.Duration), "timeout", "Test flag") },
+			value:    "999999999h",
+			err:      `invalid value for --timeout from $APP_TIMEOUT: time: invalid duration "999999999h"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(WithEnvVarPrefix("APP_"))
+			tt.register(p)
+			for _, name := range p.EnvVars() {
+				t.Setenv(name, tt.value)
+			}
+
+			err := p.ParseArgs(nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	t.Run("FloatInfAllowed", func(t *testing.T) {
+		t.Setenv("APP_RATIO", "Inf")
+
+		var f float64
+		p := New(WithEnvVarPrefix("APP_"))
+		p.Float(&f, 64, "ratio", "Test flag")
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.True(t, math.IsInf(f, 1))
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type UnknownFlagError struct {
+	Name  string
+	Short bool
+}
+
+func (e *UnknownFlagError) Error() string {
+	if e.Short {
+		return fmt.Sprintf("unknown flag: -%s", e.Name)
+	}
+	return fmt.Sprintf("unknown flag: --%s", e.Name)
+}
+
+type MissingFlagError struct {
+	Name string
+}
+
+func (e *MissingFlagError) Error() string {
+	return fmt.Sprintf("missing required flag: --%s", e.Name)
+}
+
+type MissingArgumentError struct {
+	Name string
+}
+
+func (e *MissingArgumentError) Error() string {
+	return fmt.Sprintf("missing required argument: %s", e.Name)
+}
+
+type InvalidValueError struct {
+	Name   string
+	EnvVar string
+	Err    error
+}
+
+func (e *InvalidValueError) Error() string {
+	if e.EnvVar != "" {
+		return fmt.Sprintf("invalid value for --%s from $%s: %v", e.Name, e.EnvVar, e.Err)
+	}
+	return fmt.Sprintf("invalid value for --%s: %v", e.Name, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+type InfoRequestedError struct {
+	Name    string
+	Content string
+}
+
+func (e *InfoRequestedError) Error() string {
+	return fmt.Sprintf("info requested: --%s", e.Name)
+}
+
+func (e *InfoRequestedError) Unwrap() error {
+	return ErrInfo
+}
+
+type MissingValueError struct {
+	Name     string
+	Expected string
+}
+
+func (e *MissingValueError) Error() string {
+	return fmt.Sprintf("--%s requires %s", e.Name, e.Expected)
+}
+
+type UnexpectedArgumentsError struct {
+	Args []string
+}
+
+func (e *UnexpectedArgumentsError) Error() string {
+	if len(e.Args) == 1 {
+		return fmt.Sprintf("unexpected argument: %s", e.Args[0])
+	}
+	return fmt.Sprintf("unexpected arguments: %s", strings.Join(e.Args, " "))
+}
+
+type UnknownCommandError struct {
+	Name       string
+	Suggestion string
+	Available  []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("unknown command %q, did you mean %q?", e.Name, e.Suggestion)
+	}
+	return fmt.Sprintf("unknown command %q, available: %s", e.Name, strings.Join(e.Available, ", "))
+}
+
+type jsonError struct {
+	Type    string `json:"type"`
+	Flag    string `json:"flag,omitempty"`
+	Message string `json:"message"`
+}
+
+func newJSONError(err error) jsonError {
+	je := jsonError{
+		Type:    "error",
+		Message: err.Error(),
+	}
+
+	var (
+		unknownFlagErr  *UnknownFlagError
+		missingFlagErr  *MissingFlagError
+		missingArgErr   *MissingArgumentError
+		invalidValueErr *InvalidValueError
+		missingValueErr *MissingValueError
+		unexpectedErr   *UnexpectedArgumentsError
+		unknownCmdErr   *UnknownCommandError
+	)
+
+	switch {
+	case errors.As(err, &unknownFlagErr):
+		je.Type = "unknown_flag"
+		je.Flag = unknownFlagErr.Name
+	case errors.As(err, &missingFlagErr):
+		je.Type = "missing_required"
+		je.Flag = missingFlagErr.Name
+	case errors.As(err, &missingArgErr):
+		je.Type = "missing_argument"
+	case errors.As(err, &invalidValueErr):
+		je.Type = "invalid_value"
+		je.Flag = invalidValueErr.Name
+	case errors.As(err, &missingValueErr):
+		je.Type = "missing_value"
+		je.Flag = missingValueErr.Name
+	case errors.As(err, &unexpectedErr):
+		je.Type = "unexpected_argument"
+	case errors.As(err, &unknownCmdErr):
+		je.Type = "unknown_command"
+	}
+
+	return je
+}
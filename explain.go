@@ -0,0 +1,203 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExplainArgs mirrors the classification done by parse without setting any
+// flag values, so it has to be kept in sync with it.
+func (p *Parser) ExplainArgs(args []string, w io.Writer) {
+	tw := p.newHelpTabWriter(w)
+	defer tw.Flush()
+
+	p.explainArgs(args, func(arg, format string, a ...any) {
+		fmt.Fprintf(tw, "%s\t%s\n", arg, fmt.Sprintf(format, a...))
+	})
+}
+
+func (p *Parser) explainArgs(args []string, explain func(arg, format string, a ...any)) {
+	describe := func(name string) string {
+		f, err := p.lookupArgFlag(name)
+		if err != nil {
+			return err.Error()
+		}
+		return "flag --" + f.getName()
+	}
+
+	canonical := func(name string) string {
+		if f, err := p.lookupArgFlag(name); err == nil {
+			return f.getName()
+		}
+		return name
+	}
+
+	argIndex := 0
+
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+
+		if p.passThrough && p.isUnknownFlag(arg) {
+			explain(arg, "pass-through (unknown flag)")
+			for _, arg := range args {
+				explain(arg, "pass-through")
+			}
+			return
+		}
+
+		if p.slashFlags && strings.HasPrefix(arg, "/") {
+			if name, value, found := strings.Cut(arg[1:], ":"); found {
+				explain(arg, "%s, value %q", describe(name), value)
+			} else {
+				explain(arg, "%s, value %q", describe(name), "true")
+			}
+			continue
+		}
+
+		if r, rest, ok := cutShortFlag(arg); ok {
+			f := p.lookupShortFlag(r)
+			switch {
+			case f == nil:
+				explain(arg, "unknown flag -%c", r)
+			case strings.HasPrefix(rest, "="):
+				explain(arg, "flag --%s, value %q", f.getName(), rest[1:])
+			case rest != "" && !f.isBoolFlag():
+				explain(arg, "flag --%s, value %q", f.getName(), rest)
+			case rest != "":
+				args = args[p.explainShortCluster(arg, args, explain):]
+			case !p.hasValueArg(f, args):
+				explain(arg, "flag --%s", f.getName())
+			default:
+				explain(arg, "flag --%s", f.getName())
+				explain(args[0], "value for --%s", f.getName())
+				args = args[1:]
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(arg, "--") {
+			if cmd := p.lookupCommand(arg); cmd != nil {
+				explain(arg, "command %s", cmd.name)
+				cmd.parser.explainArgs(args, explain)
+				return
+			}
+			if argIndex < len(p.args) {
+				explain(arg, "argument %s", p.args[argIndex].getName())
+				argIndex++
+				continue
+			}
+			if p.unknownArgHandler != nil {
+				explain(arg, "positional")
+				continue
+			}
+			if len(p.commands) != 0 {
+				explain(arg, "unknown command")
+			} else {
+				explain(arg, "unexpected argument")
+			}
+			for _, arg := range args {
+				explain(arg, "ignored")
+			}
+			return
+		}
+
+		name := strings.TrimPrefix(arg, "--")
+
+		if name == "" {
+			explain(arg, "terminator")
+			for _, arg := range args {
+				if argIndex < len(p.args) {
+					explain(arg, "argument %s", p.args[argIndex].getName())
+					argIndex++
+					continue
+				}
+				explain(arg, "rest argument")
+			}
+			return
+		}
+
+		if name, value, found := strings.Cut(name, "="); found {
+			explain(arg, "%s, value %q", describe(name), value)
+			continue
+		}
+
+		if p.lenientAssignment && len(args) != 0 && strings.HasPrefix(args[0], "=") {
+			explain(arg, "%s", describe(name))
+			explain(args[0], "assignment")
+			if args[0] == "=" && len(args) > 1 {
+				args = args[1:]
+				explain(args[0], "value for --%s", canonical(name))
+			}
+			args = args[1:]
+			continue
+		}
+
+		f, _ := p.lookupArgFlag(name)
+		if !p.hasValueArg(f, args) {
+			explain(arg, "%s", describe(name))
+			continue
+		}
+
+		explain(arg, "%s", describe(name))
+		explain(args[0], "value for --%s", canonical(name))
+		args = args[1:]
+	}
+}
+
+// explainShortCluster mirrors setShortCluster, returning the number of args
+// consumed.
+func (p *Parser) explainShortCluster(arg string, args []string, explain func(arg, format string, a ...any)) int {
+	var names []string
+
+	cluster := arg[1:]
+	for cluster != "" {
+		r, size := utf8.DecodeRuneInString(cluster)
+		cluster = cluster[size:]
+
+		f := p.lookupShortFlag(r)
+		switch {
+		case r == '=':
+			explain(arg, "unexpected argument")
+			return 0
+		case f == nil && p.ignoreUnknown:
+			continue
+		case f == nil:
+			explain(arg, "unknown flag -%c", r)
+			return 0
+		case f.isBoolFlag():
+			names = append(names, "--"+f.getName())
+			continue
+		case cluster != "":
+			explain(arg, "flag -%c requires a value, so it must be the last one", r)
+			return 0
+		}
+
+		names = append(names, "--"+f.getName())
+		explain(arg, "flags %s", strings.Join(names, ", "))
+		if len(args) == 0 || p.isLongFlag(args[0]) {
+			return 0
+		}
+		explain(args[0], "value for --%s", f.getName())
+		return 1
+	}
+
+	explain(arg, "flags %s", strings.Join(names, ", "))
+	return 0
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParserExplainArgs(t *testing.T) {
+	var (
+		v    bool
+		n    int
+		name string
+		rest []string
+	)
+
+	p := New()
+	p.Bool(&v, "verbose", "Test flag").Short('v')
+	p.Int(&n, "count", "Test flag").Short('n')
+	p.String(&name, "name", "Test flag")
+	p.RestArgs(&rest)
+
+	buf := bytes.NewBuffer(nil)
+	p.ExplainArgs([]string{"-v", "-n", "-5", "--name=foo", "--count", "3", "--unknown", "--", "cmd", "--its-own"}, buf)
+
+	const expected = "-v          flag --verbose\n" +
+		"-n          flag --count\n" +
+		"-5          value for --count\n" +
+		"--name=foo  flag --name, value \"foo\"\n" +
+		"--count     flag --count\n" +
+		"3           value for --count\n" +
+		"--unknown   unknown flag: --unknown\n" +
+		"--          terminator\n" +
+		"cmd         rest argument\n" +
+		"--its-own   rest argument\n"
+
+	assert.Equal(t, expected, buf.String())
+	assert.False(t, v)
+	assert.Zero(t, n)
+	assert.Empty(t, rest)
+}
+
+func TestParserExplainArgsCommand(t *testing.T) {
+	var (
+		v    bool
+		port int
+	)
+
+	p := New()
+	p.Bool(&v, "verbose", "Test flag")
+	serve := p.Command("serve", "Test command")
+	serve.Int(&port, "port", "Test flag")
+
+	buf := bytes.NewBuffer(nil)
+	p.ExplainArgs([]string{"--verbose", "serve", "--port", "8080"}, buf)
+
+	const expected = "--verbose  flag --verbose\n" +
+		"serve      command serve\n" +
+		"--port     flag --port\n" +
+		"8080       value for --port\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestParserExplainArgsShortCluster(t *testing.T) {
+	var (
+		v, q   bool
+		output string
+	)
+
+	p := New()
+	p.Bool(&v, "verbose", "Test flag").Short('v')
+	p.Bool(&q, "quiet", "Test flag").Short('q')
+	p.String(&output, "output", "Test flag").Short('o')
+
+	buf := bytes.NewBuffer(nil)
+	p.ExplainArgs([]string{"-vq", "-qo", "out.txt", "-oq"}, buf)
+
+	const expected = "-vq      flags --verbose, --quiet\n" +
+		"-qo      flags --quiet, --output\n" +
+		"out.txt  value for --output\n" +
+		"-oq      flag --output, value \"q\"\n"
+
+	assert.Equal(t, expected, buf.String())
+}
@@ -15,145 +15,1208 @@
 package flenv
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	errEmptyString = errors.New("empty string")
 )
 
+type valueSource int
+
+const (
+	sourceNone valueSource = iota
+	sourceDefault
+	sourceConfig
+	sourceSecret
+	sourceEnv
+	sourceProfile
+	sourceArgs
+)
+
+func (s valueSource) String() string {
+	switch s {
+	case sourceDefault:
+		return "default"
+	case sourceConfig:
+		return "config"
+	case sourceSecret:
+		return "secret"
+	case sourceEnv:
+		return "env"
+	case sourceProfile:
+		return "profile"
+	case sourceArgs:
+		return "args"
+	default:
+		return "none"
+	}
+}
+
+type Pair struct {
+	Key   string
+	Value string
+}
+
+type envCondition struct {
+	name  string
+	value string
+}
+
 type Flag[T any] struct {
-	target *T
-	isBool bool
+	parser     *Parser
+	target     *T
+	isBool     bool
+	positional bool
+
+	name           string
+	short          rune
+	envVarName     string
+	envPresence    bool
+	envIndexed     bool
+	secretKey      string
+	helpMessage    string
+	placeholder    string
+	placeholderSet bool
+	example        string
+	typeName       string
+
+	defaultValue        T
+	defaultValueSet     bool
+	defaultOverridesEnv bool
+	defaultEnvVarName   string
+	defaultFunc         func() (T, error)
+
+	required    bool
+	requiredIf  *envCondition
+	hidden      bool
+	obsolete    string
+	interpolate bool
+	// template and interpolated keep the value before and after the last
+	// interpolation, so it could be redone
+	template     string
+	interpolated string
+	fileRef      bool
+	stdin        bool
+	keepNewline  bool
+	noAbbrev     bool
+	set          bool
+	source       valueSource
+
+	parseFunc  func(string) (T, error)
+	keywords   map[string]T
+	accumulate func(T, T) T
+	redactFunc func(T) string
+	formatFunc func(T) string
+	// formatArgsFunc formats values of flags taking a single item per
+	// occurrence as the values of repeated occurrences
+	formatArgsFunc func(T) []string
+	validators     []func(T) error
+	transforms     []func(T) (T, error)
+}
+
+func (f *Flag[T]) Short(r rune) *Flag[T] {
+	if f.positional {
+		panic("setting short flag for a positional argument is not possible")
+	}
+
+	if f.short != 0 {
+		panic(fmt.Sprintf("short flag for --%s is already set", f.name))
+	}
+
+	if f.parser != nil {
+		f.parser.registerShortFlag(r, f)
+	}
+
+	f.short = r
+	return f
+}
+
+func (f *Flag[T]) Env(name string) *Flag[T] {
+	if f.positional {
+		panic("setting envvar for a positional argument is not possible")
+	}
+
+	f.envVarName = name
+	return f
+}
+
+func (f *Flag[T]) EnvPresenceImpliesTrue() *Flag[T] {
+	if !f.isBool {
+		panic("enabling env presence mode for a non-bool flag is not possible")
+	}
+
+	f.envPresence = true
+	return f
+}
+
+func (f *Flag[T]) EnvIndexed() *Flag[T] {
+	if f.accumulate == nil {
+		panic("enabling indexed env mode for a non-slice flag is not possible")
+	}
+
+	f.envIndexed = true
+	return f
+}
+
+func (f *Flag[T]) AllowExtendedUnits() *Flag[T] {
+	parseFunc, ok := any(&f.parseFunc).(*func(string) (time.Duration, error))
+	if !ok {
+		panic("allowing extended units for a non-duration flag is not possible")
+	}
+
+	*parseFunc = parseExtendedDuration
+	return f
+}
+
+func (f *Flag[T]) FiniteOnly() *Flag[T] {
+	if _, ok := any(f.target).(*float64); !ok {
+		panic("rejecting non-finite values of a non-float flag is not possible")
+	}
+
+	f.validators = append(f.validators, func(v T) error {
+		if n := any(v).(float64); math.IsInf(n, 0) || math.IsNaN(n) {
+			return errors.New("must be a finite number")
+		}
+		return nil
+	})
+	return f
+}
+
+func (f *Flag[T]) NonNegative() *Flag[T] {
+	if _, ok := any(f.target).(*time.Duration); !ok {
+		panic("rejecting negative values of a non-duration flag is not possible")
+	}
+
+	f.validators = append(f.validators, func(v T) error {
+		if any(v).(time.Duration) < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	return f
+}
+
+func (f *Flag[T]) AllowSIUnits() *Flag[T] {
+	parseFunc, ok := any(&f.parseFunc).(*func(string) (int, error))
+	if !ok {
+		panic("allowing SI units for a non-int flag is not possible")
+	}
+
+	*parseFunc = parseSIInt
+	return f
+}
+
+func (f *Flag[T]) AllowUnix() *Flag[T] {
+	return f.allowUnixTimestamps(func(n int64) time.Time {
+		return time.Unix(n, 0)
+	})
+}
+
+func (f *Flag[T]) AllowUnixMillis() *Flag[T] {
+	return f.allowUnixTimestamps(time.UnixMilli)
+}
+
+func (f *Flag[T]) allowUnixTimestamps(fromUnix func(int64) time.Time) *Flag[T] {
+	parseFunc, ok := any(&f.parseFunc).(*func(string) (time.Time, error))
+	if !ok {
+		panic("allowing Unix timestamps for a non-time flag is not possible")
+	}
+
+	parseLayout := *parseFunc
+	*parseFunc = func(s string) (time.Time, error) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return fromUnix(n).UTC(), nil
+		}
+		return parseLayout(s)
+	}
+	return f
+}
+
+func (f *Flag[T]) FromSecret(key string) *Flag[T] {
+	if f.positional {
+		panic("reading a positional argument from secrets is not possible")
+	}
+
+	f.secretKey = key
+	return f
+}
+
+func (f *Flag[T]) Placeholder(placeholder string) *Flag[T] {
+	if f.isBool {
+		panic("setting placeholder for a bool flag is not possible")
+	}
+
+	f.placeholder = placeholder
+	f.placeholderSet = true
+	return f
+}
+
+func (f *Flag[T]) Example(example string) *Flag[T] {
+	f.example = example
+	return f
+}
+
+func (f *Flag[T]) Default(v T) *Flag[T] {
+	if f.isBool {
+		panic("setting default value for a bool flag is not possible")
+	}
+
+	if f.required || f.requiredIf != nil {
+		panic("setting default value for a required flag is not possible")
+	}
+
+	f.defaultValue = v
+	f.defaultValueSet = true
+	return f
+}
+
+// DefaultFuncErr sets a default computed at parse time, only if the flag
+// isn't set from any other source. The function could therefore rely on
+// the values of flags registered earlier.
+func (f *Flag[T]) DefaultFuncErr(fn func() (T, error)) *Flag[T] {
+	if f.isBool {
+		panic("setting default value for a bool flag is not possible")
+	}
+
+	if f.required || f.requiredIf != nil {
+		panic("setting default value for a required flag is not possible")
+	}
+
+	f.defaultFunc = fn
+	return f
+}
+
+// DefaultOverridesEnv reverses the default/env precedence for the flag, so
+// the default value, if set, wins over the envvar value. The command line
+// still wins over both.
+func (f *Flag[T]) DefaultOverridesEnv() *Flag[T] {
+	if f.positional {
+		panic("setting envvar precedence for a positional argument is not possible")
+	}
+
+	f.defaultOverridesEnv = true
+	return f
+}
+
+func (f *Flag[T]) Required() *Flag[T] {
+	if f.isBool {
+		panic("making a bool flag required is not possible")
+	}
+
+	if f.defaultValueSet || f.defaultFunc != nil {
+		panic("making a flag with default value required is not possible")
+	}
+
+	f.required = true
+	return f
+}
+
+func (f *Flag[T]) RequiredWhenEnv(name, value string) *Flag[T] {
+	if f.positional {
+		panic("making a positional argument required by envvar is not possible")
+	}
+
+	if f.isBool {
+		panic("making a bool flag required is not possible")
+	}
+
+	if f.defaultValueSet || f.defaultFunc != nil {
+		panic("making a flag with default value required is not possible")
+	}
+
+	f.requiredIf = &envCondition{name: name, value: value}
+	return f
+}
+
+func (f *Flag[T]) Interpolate() *Flag[T] {
+	if f.positional {
+		panic("interpolating a positional argument is not possible")
+	}
+
+	if _, ok := any(f.target).(*string); !ok {
+		panic("interpolating a non-string flag is not possible")
+	}
+
+	f.interpolate = true
+	return f
+}
+
+func (f *Flag[T]) ASCIIOnly() *Flag[T] {
+	return f.addStringValidator("restricting a non-string flag to ASCII is not possible", func(s string) error {
+		for i, r := range []rune(s) {
+			if r > unicode.MaxASCII {
+				return fmt.Errorf("non-ASCII character %q at position %d", r, i)
+			}
+		}
+		return nil
+	})
+}
+
+func (f *Flag[T]) NoControlChars() *Flag[T] {
+	return f.addStringValidator("restricting control characters of a non-string flag is not possible", func(s string) error {
+		for i, r := range []rune(s) {
+			if unicode.IsControl(r) {
+				return fmt.Errorf("control character %q at position %d", r, i)
+			}
+		}
+		return nil
+	})
+}
+
+func (f *Flag[T]) Min(n int) *Flag[T] {
+	return f.addIntValidator("setting minimum of a non-int flag is not possible", func(v int) error {
+		if v < n {
+			return fmt.Errorf("must be at least %d", n)
+		}
+		return nil
+	})
+}
+
+func (f *Flag[T]) Max(n int) *Flag[T] {
+	return f.addIntValidator("setting maximum of a non-int flag is not possible", func(v int) error {
+		if v > n {
+			return fmt.Errorf("must be at most %d", n)
+		}
+		return nil
+	})
+}
+
+func (f *Flag[T]) MultipleOf(n int) *Flag[T] {
+	if n <= 0 {
+		panic("requiring a multiple of a non-positive number is not possible")
+	}
+
+	return f.addIntValidator("requiring a multiple for a non-int flag is not possible", func(v int) error {
+		if v%n != 0 {
+			return fmt.Errorf("must be a multiple of %d", n)
+		}
+		return nil
+	})
+}
+
+func (f *Flag[T]) RequireResolvableHost() *Flag[T] {
+	if _, ok := any(f.target).(**url.URL); !ok {
+		panic("requiring a resolvable host for a non-URL flag is not possible")
+	}
+
+	f.validators = append(f.validators, func(v T) error {
+		u := any(v).(*url.URL)
+		if u == nil {
+			return nil
+		}
+
+		var (
+			resolver HostResolver = net.DefaultResolver
+			ctx                   = context.Background()
+		)
+		if f.parser != nil {
+			if f.parser.hostResolver != nil {
+				resolver = f.parser.hostResolver
+			}
+			ctx = f.parser.context()
+		}
+
+		if _, err := resolver.LookupHost(ctx, u.Hostname()); err != nil {
+			return fmt.Errorf("host %s does not resolve: %w", u.Hostname(), err)
+		}
+		return nil
+	})
+	return f
+}
+
+func (f *Flag[T]) JSONSchema(schema string) *Flag[T] {
+	if f.typeName != "json" {
+		panic("validating a non-JSON flag against a JSON schema is not possible")
+	}
+
+	var s valueSchema
+	dec := json.NewDecoder(strings.NewReader(schema))
+	// unsupported keywords would silently let any value through
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		panic(fmt.Sprintf("invalid JSON schema for --%s: %s", f.name, err))
+	}
+
+	parseJSON := f.parseFunc
+	f.parseFunc = func(raw string) (T, error) {
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		if err := s.validate("$", v); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		return parseJSON(raw)
+	}
+	return f
+}
+
+func (f *Flag[T]) addIntValidator(panicMsg string, validate func(int) error) *Flag[T] {
+	if _, ok := any(f.target).(*int); !ok {
+		panic(panicMsg)
+	}
+
+	f.validators = append(f.validators, func(v T) error {
+		return validate(any(v).(int))
+	})
+	return f
+}
+
+func (f *Flag[T]) Length(min, max int) *Flag[T] {
+	return f.addStringValidator("restricting length of a non-string flag is not possible", func(s string) error {
+		n := utf8.RuneCountInString(s)
+		switch {
+		case max < 0 && n < min:
+			return fmt.Errorf("must be at least %d characters", min)
+		case max >= 0 && (n < min || n > max):
+			return fmt.Errorf("must be %d to %d characters", min, max)
+		}
+		return nil
+	})
+}
+
+func (f *Flag[T]) addStringValidator(panicMsg string, validate func(string) error) *Flag[T] {
+	if _, ok := any(f.target).(*string); !ok {
+		panic(panicMsg)
+	}
+
+	f.validators = append(f.validators, func(v T) error {
+		return validate(any(v).(string))
+	})
+	return f
+}
+
+func (f *Flag[T]) TrimSpace() *Flag[T] {
+	return f.addStringTransform("trimming a non-string flag is not possible", func(s string) (string, error) {
+		return strings.TrimSpace(s), nil
+	})
+}
+
+func (f *Flag[T]) ToLower() *Flag[T] {
+	return f.addStringTransform("lowercasing a non-string flag is not possible", func(s string) (string, error) {
+		return strings.ToLower(s), nil
+	})
+}
+
+func (f *Flag[T]) Abs() *Flag[T] {
+	return f.addStringTransform("resolving an absolute path of a non-string flag is not possible", func(s string) (string, error) {
+		if s == "" {
+			return s, nil
+		}
+		return filepath.Abs(s)
+	})
+}
+
+func (f *Flag[T]) addStringTransform(panicMsg string, transform func(string) (string, error)) *Flag[T] {
+	if _, ok := any(f.target).(*string); !ok {
+		panic(panicMsg)
+	}
+
+	f.transforms = append(f.transforms, func(v T) (T, error) {
+		s, err := transform(any(v).(string))
+		return any(s).(T), err
+	})
+	return f
+}
+
+func (f *Flag[T]) AllowFileRef() *Flag[T] {
+	f.fileRef = true
+	return f
+}
+
+func (f *Flag[T]) AllowStdin() *Flag[T] {
+	f.stdin = true
+	return f
+}
+
+func (f *Flag[T]) PreserveNewlines() *Flag[T] {
+	f.keepNewline = true
+	return f
+}
+
+func (f *Flag[T]) NoAbbrev() *Flag[T] {
+	if f.positional {
+		panic("disabling abbreviations for a positional argument is not possible")
+	}
+
+	f.noAbbrev = true
+	return f
+}
+
+func (f *Flag[T]) Keyword(word string, value T) *Flag[T] {
+	if f.keywords == nil {
+		f.keywords = make(map[string]T)
+	}
+
+	f.keywords[word] = value
+	return f
+}
+
+func (f *Flag[T]) ParseFunc(fn func(string) (T, error)) *Flag[T] {
+	if fn == nil {
+		panic("setting a nil parse func is not possible")
+	}
+
+	f.parseFunc = fn
+	return f
+}
+
+func (f *Flag[T]) Redact(fn func(T) string) *Flag[T] {
+	f.redactFunc = fn
+	return f
+}
+
+func (f *Flag[T]) Obsolete(message string) *Flag[T] {
+	if f.positional {
+		panic("making a positional argument obsolete is not possible")
+	}
+
+	f.obsolete = message
+	return f
+}
+
+func (f *Flag[T]) Hidden() *Flag[T] {
+	if f.positional {
+		panic("hiding a positional argument is not possible")
+	}
+
+	f.hidden = true
+	return f
+}
+
+func (f *Flag[T]) isRequired() bool {
+	return f.required
+}
+
+func (f *Flag[T]) isRequiredByEnv(lookupEnv func(string) (string, bool)) bool {
+	if f.requiredIf == nil {
+		return false
+	}
+
+	val, ok := lookupEnv(f.requiredIf.name)
+	return ok && val == f.requiredIf.value
+}
+
+func (f *Flag[T]) isObsolete() bool {
+	return f.obsolete != ""
+}
+
+func (f *Flag[T]) isHidden() bool {
+	return f.hidden
+}
+
+func (f *Flag[T]) isRepeatable() bool {
+	return f.accumulate != nil
+}
+
+func (f *Flag[T]) acceptsEmpty() bool {
+	_, err := f.parseKeyword("")
+	return err == nil
+}
+
+func (f *Flag[T]) isSet() bool {
+	return f.set
+}
+
+func (f *Flag[T]) getName() string {
+	return f.name
+}
+
+func (f *Flag[T]) getShort() rune {
+	return f.short
+}
+
+func (f *Flag[T]) bind(p *Parser) {
+	f.parser = p
+}
+
+func (f *Flag[T]) getTypeName() string {
+	return f.typeName
+}
+
+func (f *Flag[T]) getHelpMessage() string {
+	return f.helpMessage
+}
+
+func (f *Flag[T]) isBoolFlag() bool {
+	return f.isBool
+}
+
+func (f *Flag[T]) getEnvVarName() string {
+	return f.envVarName
+}
+
+func (f *Flag[T]) getSecretKey() string {
+	return f.secretKey
+}
+
+// getEnvVarNames returns all envvars consulted for the flag, with indexed
+// envvars reported as a NAME_* pattern.
+func (f *Flag[T]) getEnvVarNames() []string {
+	var names []string
+
+	switch {
+	case f.envVarName == "":
+	case f.envIndexed:
+		names = append(names, f.envVarName+"_*")
+	default:
+		names = append(names, f.envVarName)
+	}
+
+	if f.defaultEnvVarName != "" {
+		names = append(names, f.defaultEnvVarName)
+	}
+	if f.requiredIf != nil {
+		names = append(names, f.requiredIf.name)
+	}
+
+	return names
+}
+
+func (f *Flag[T]) matchesEnvVar(name string) bool {
+	if f.envVarName == "" {
+		return false
+	}
+
+	if name == f.envVarName {
+		return true
+	}
+
+	if !f.envIndexed {
+		return false
+	}
+
+	idx, ok := strings.CutPrefix(name, f.envVarName+"_")
+	if !ok {
+		return false
+	}
+
+	_, err := strconv.ParseUint(idx, 10, 0)
+	return err == nil
+}
+
+func (f *Flag[T]) getShortDescription() string {
+	if f.isBool {
+		return fmt.Sprintf("--%s", f.name)
+	}
+	return fmt.Sprintf("--%s=%s", f.name, f.getPlaceholder())
+}
+
+func (f *Flag[T]) getPlaceholder() string {
+	if !f.placeholderSet && f.parser != nil && f.parser.typeHints {
+		return "<" + f.typeName + ">"
+	}
+	return f.placeholder
+}
+
+func (f *Flag[T]) getLongDescription() string {
+	b := &strings.Builder{}
+
+	switch {
+	case f.short != 0:
+		fmt.Fprintf(b, "  -%c, %s\t%s", f.short, f.getShortDescription(), f.helpMessage)
+	case f.parser != nil && len(f.parser.shortIndex) != 0:
+		// reserve the short form column, so the long forms line up
+		fmt.Fprintf(b, "      %s\t%s", f.getShortDescription(), f.helpMessage)
+	default:
+		fmt.Fprintf(b, "  %s\t%s", f.getShortDescription(), f.helpMessage)
+	}
+
+	requiredLabel, defaultLabelFormat := "(required)", "(default: %s)"
+	if f.parser != nil {
+		requiredLabel, defaultLabelFormat = f.parser.requiredLabel, f.parser.defaultLabelFormat
+	}
+
+	switch {
+	case f.obsolete != "":
+		fmt.Fprint(b, " (removed)")
+	case f.required:
+		fmt.Fprintf(b, " %s", requiredLabel)
+	case f.requiredIf != nil:
+		fmt.Fprintf(b, " %s", conditionalLabel(requiredLabel, fmt.Sprintf("when $%s=%s", f.requiredIf.name, f.requiredIf.value)))
+	case f.defaultValueSet:
+		fmt.Fprintf(b, " "+defaultLabelFormat, f.formatValue(f.defaultValue))
+	}
+
+	if f.envVarName != "" {
+		fmt.Fprintf(b, " [$%s]", f.envVarName)
+	}
+
+	return b.String()
+}
+
+// conditionalLabel appends the condition to the required label, inside its
+// closing bracket if any, e.g. (required when $MODE=prod).
+func conditionalLabel(label, condition string) string {
+	if n := len(label); n != 0 && (label[n-1] == ')' || label[n-1] == ']') {
+		return label[:n-1] + " " + condition + label[n-1:]
+	}
+	return label + " " + condition
+}
+
+func (f *Flag[T]) getDetailedDescription() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintln(b, f.getShortDescription())
+	if f.helpMessage != "" {
+		fmt.Fprintf(b, "  %s\n", f.helpMessage)
+	}
+
+	if f.obsolete != "" {
+		fmt.Fprintf(b, "  Removed: %s\n", f.obsolete)
+	}
+
+	if f.required {
+		fmt.Fprintln(b, "  Required: yes")
+	}
 
-	name        string
-	envVarName  string
-	helpMessage string
-	placeholder string
+	if f.defaultValueSet {
+		fmt.Fprintf(b, "  Default: %s\n", f.formatValue(f.defaultValue))
+	}
 
-	defaultValue    T
-	defaultValueSet bool
+	if f.envVarName != "" {
+		fmt.Fprintf(b, "  Environment: $%s\n", f.envVarName)
+	}
 
-	required bool
-	set      bool
+	if f.example != "" {
+		fmt.Fprintf(b, "  Example: --%s=%s\n", f.name, f.example)
+	}
 
-	parseFunc func(string) (T, error)
+	return b.String()
 }
 
-func (f *Flag[T]) Env(name string) *Flag[T] {
-	f.envVarName = name
-	return f
+func (f *Flag[T]) getDefaultValueString() (string, bool) {
+	if !f.defaultValueSet {
+		return "", false
+	}
+
+	return f.formatValue(f.defaultValue), true
 }
 
-func (f *Flag[T]) Placeholder(placeholder string) *Flag[T] {
-	if f.isBool {
-		panic("setting placeholder for a bool flag is not possible")
+func (f *Flag[T]) equalsDefault() bool {
+	return f.defaultValueSet && reflect.DeepEqual(*f.target, f.defaultValue)
+}
+
+func (f *Flag[T]) isSensitive() bool {
+	return f.secretKey != "" || f.redactFunc != nil
+}
+
+func (f *Flag[T]) isAbbreviable() bool {
+	return !f.noAbbrev
+}
+
+func (f *Flag[T]) isInterpolated() bool {
+	return f.interpolate
+}
+
+func (f *Flag[T]) getValueString() string {
+	return f.formatValue(*f.target)
+}
+
+// formatValue renders the value in a form accepted by the flag parser.
+func (f *Flag[T]) formatValue(v T) string {
+	if f.formatFunc != nil {
+		return f.formatFunc(v)
 	}
 
-	f.placeholder = placeholder
-	return f
+	return fmt.Sprint(v)
 }
 
-func (f *Flag[T]) Default(v T) *Flag[T] {
-	if f.isBool {
-		panic("setting default value for a bool flag is not possible")
+func (f *Flag[T]) getDisplayValue() string {
+	if f.redactFunc != nil {
+		return f.redactFunc(*f.target)
 	}
 
-	if f.required {
-		panic("setting default value for a required flag is not possible")
+	if f.isSensitive() {
+		return "****"
 	}
 
-	f.defaultValue = v
-	f.defaultValueSet = true
-	return f
+	return f.getValueString()
 }
 
-func (f *Flag[T]) Required() *Flag[T] {
-	if f.isBool {
-		panic("making a bool flag required is not possible")
+// getArgValues returns the display value as the values of the flag's
+// occurrences, which are repeated for flags taking a single item per
+// occurrence.
+func (f *Flag[T]) getArgValues() []string {
+	if f.formatArgsFunc == nil || f.isSensitive() {
+		return []string{f.getDisplayValue()}
 	}
 
-	if f.defaultValueSet {
-		panic("making a flag with default value required is not possible")
+	if values := f.formatArgsFunc(*f.target); len(values) != 0 {
+		return values
 	}
+	return []string{f.getDisplayValue()}
+}
 
-	f.required = true
-	return f
+func (f *Flag[T]) setInterpolatedValue(s string) error {
+	if f.isTemplate(*f.target) {
+		val := any(s).(T)
+		if err := f.validate(val); err != nil {
+			return err
+		}
+
+		val, err := f.transform(val)
+		if err != nil {
+			return err
+		}
+		s = any(val).(string)
+	}
+
+	target := any(f.target).(*string)
+	f.template, f.interpolated = *target, s
+	*target = s
+
+	return nil
 }
 
-func (f *Flag[T]) isRequired() bool {
-	return f.required
+// resetInterpolation brings the uninterpolated value back, unless the value
+// has been changed since the interpolation.
+func (f *Flag[T]) resetInterpolation() {
+	if target, ok := any(f.target).(*string); ok && f.interpolate && *target == f.interpolated {
+		*target = f.template
+	}
 }
 
-func (f *Flag[T]) isSet() bool {
-	return f.set
+func (f *Flag[T]) getSource() valueSource {
+	return f.source
 }
 
-func (f *Flag[T]) getName() string {
-	return f.name
+func (f *Flag[T]) setValue(val T, source valueSource) {
+	*f.target = val
+	f.set = true
+	f.source = source
 }
 
-func (f *Flag[T]) getShortDescription() string {
-	if f.isBool {
-		return fmt.Sprintf("--%s", f.name)
+func (f *Flag[T]) snapshot() func() {
+	val, set, source := *f.target, f.set, f.source
+	template, interpolated := f.template, f.interpolated
+	return func() {
+		*f.target = val
+		f.set = set
+		f.source = source
+		f.template, f.interpolated = template, interpolated
 	}
-	return fmt.Sprintf("--%s=%s", f.name, f.placeholder)
 }
 
-func (f *Flag[T]) getLongDescription() string {
-	b := &strings.Builder{}
+func (f *Flag[T]) setValueFromString(s string) error {
+	return f.parseValue(s, sourceArgs)
+}
+
+func (f *Flag[T]) parseValue(s string, source valueSource) error {
+	if f.obsolete != "" && (source == sourceArgs || source == sourceEnv) {
+		return fmt.Errorf("--%s has been removed: %s", f.name, f.obsolete)
+	}
+
+	s, err := f.readValue(s)
+	if err != nil {
+		return &InvalidValueError{Name: f.name, Err: err}
+	}
+
+	if f.isBool && source == sourceEnv && f.parser != nil && f.parser.strictBoolEnv && s != "true" && s != "false" {
+		return &InvalidValueError{Name: f.name, Err: fmt.Errorf("%q is not exactly true or false", s)}
+	}
+
+	val, err := f.parseKeyword(s)
+	if err != nil {
+		return &InvalidValueError{Name: f.name, Err: err}
+	}
+
+	val, err = f.prepareValue(val)
+	if err != nil {
+		return err
+	}
+
+	if f.accumulate != nil && source == sourceArgs && f.source == sourceArgs {
+		// repeated command line flags accumulate, while the first one
+		// overrides the default or env value
+		val = f.accumulate(*f.target, val)
+	}
+
+	f.setValue(val, source)
+
+	return nil
+}
+
+// parseKeyword is parseFunc preceded by the keywords lookup.
+func (f *Flag[T]) parseKeyword(s string) (T, error) {
+	if val, ok := f.keywords[s]; ok {
+		return val, nil
+	}
+
+	return f.parseFunc(s)
+}
 
-	fmt.Fprintf(b, "  %s\t%s", f.getShortDescription(), f.helpMessage)
+func (f *Flag[T]) readValue(s string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
 
 	switch {
-	case f.required:
-		fmt.Fprint(b, " (required)")
-	case f.defaultValueSet:
-		fmt.Fprintf(b, " (default: %v)", f.defaultValue)
+	case f.stdin && s == "-":
+		stdin := io.Reader(os.Stdin)
+		if f.parser != nil && f.parser.stdin != nil {
+			stdin = f.parser.stdin
+		}
+		b, err = io.ReadAll(stdin)
+	case f.fileRef && strings.HasPrefix(s, "@"):
+		// @path reads the value from a file, e.g. a mounted config map
+		b, err = os.ReadFile(s[1:])
+	default:
+		return s, nil
 	}
 
-	if f.envVarName != "" {
-		fmt.Fprintf(b, " [$%s]", f.envVarName)
+	if err != nil {
+		return "", err
 	}
 
-	return b.String()
+	if f.keepNewline {
+		// multi-line values (e.g. PEM blocks) only lose the final newline
+		return strings.TrimSuffix(string(b), "\n"), nil
+	}
+
+	return strings.TrimSpace(string(b)), nil
 }
 
-func (f *Flag[T]) setValue(val T) {
-	*f.target = val
-	f.set = true
+func (f *Flag[T]) setValueFromEnv(lookupEnv func(string) (string, bool)) error {
+	if f.defaultOverridesEnv && f.defaultValueSet {
+		return nil
+	}
+
+	if f.envIndexed {
+		return f.setValueFromIndexedEnv(lookupEnv)
+	}
+
+	val, ok := lookupEnv(f.envVarName)
+	if !ok {
+		return nil
+	}
+
+	if f.envPresence {
+		val = "true"
+	}
+
+	return withEnvVar(f.parseValue(val, sourceEnv), f.envVarName)
 }
 
-func (f *Flag[T]) setValueFromString(s string) error {
-	val, err := f.parseFunc(s)
+// withEnvVar qualifies an invalid value error with the envvar it came from.
+func withEnvVar(err error, name string) error {
+	var invalidValueErr *InvalidValueError
+	if errors.As(err, &invalidValueErr) {
+		invalidValueErr.EnvVar = name
+	}
+
+	return err
+}
+
+func (f *Flag[T]) setValueFromIndexedEnv(lookupEnv func(string) (string, bool)) error {
+	var (
+		vals  T
+		found bool
+	)
+
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s_%d", f.envVarName, i)
+		s, ok := lookupEnv(name)
+		if !ok {
+			break
+		}
+
+		val, err := f.parseKeyword(s)
+		if err != nil {
+			return &InvalidValueError{Name: f.name, EnvVar: name, Err: err}
+		}
+
+		if found {
+			vals = f.accumulate(vals, val)
+		} else {
+			vals, found = val, true
+		}
+	}
+
+	if found {
+		f.setValue(vals, sourceEnv)
+	}
+
+	return nil
+}
+
+func (f *Flag[T]) setValueFromSecret(r SecretResolver) error {
+	if f.secretKey == "" || f.source > sourceSecret {
+		return nil
+	}
+
+	if r == nil {
+		return fmt.Errorf("no secret resolver configured for --%s", f.name)
+	}
+
+	val, err := r.Resolve(f.secretKey)
+	if err != nil {
+		return fmt.Errorf("resolving secret for --%s: %w", f.name, err)
+	}
+
+	return f.parseValue(val, sourceSecret)
+}
+
+func (f *Flag[T]) setValueFromDefault(lookupEnv func(string) (string, bool)) error {
+	if f.defaultEnvVarName != "" {
+		if val, ok := lookupEnv(f.defaultEnvVarName); ok {
+			return f.parseValue(val, sourceDefault)
+		}
+	}
+
+	if f.defaultValueSet {
+		val, err := f.prepareValue(f.defaultValue)
+		if err != nil {
+			return err
+		}
+		f.setValue(val, sourceDefault)
+	}
+
+	return nil
+}
+
+func (f *Flag[T]) setValueFromDefaultFunc() error {
+	if f.defaultFunc == nil || f.source > sourceDefault {
+		return nil
+	}
+
+	val, err := f.defaultFunc()
+	if err != nil {
+		return &InvalidValueError{Name: f.name, Err: fmt.Errorf("computing default: %w", err)}
+	}
+
+	val, err = f.prepareValue(val)
 	if err != nil {
 		return err
 	}
 
-	f.setValue(val)
+	f.setValue(val, sourceDefault)
+	return nil
+}
+
+// prepareValue validates and transforms val. Interpolation templates are
+// left as is, to be checked once expanded.
+func (f *Flag[T]) prepareValue(val T) (T, error) {
+	if f.isTemplate(val) {
+		return val, nil
+	}
+
+	if err := f.validate(val); err != nil {
+		return val, err
+	}
+
+	return f.transform(val)
+}
+
+// isTemplate reports whether val references other flags to be interpolated.
+func (f *Flag[T]) isTemplate(val T) bool {
+	s, ok := any(val).(string)
+	return f.interpolate && ok && interpolationRegexp.MatchString(s)
+}
+
+func (f *Flag[T]) validate(val T) error {
+	for _, validate := range f.validators {
+		if err := validate(val); err != nil {
+			return &InvalidValueError{Name: f.name, Err: err}
+		}
+	}
 
 	return nil
 }
 
-func (f *Flag[T]) setValueFromEnv() error {
-	val, ok := os.LookupEnv(f.envVarName)
+func (f *Flag[T]) transform(val T) (T, error) {
+	for _, transform := range f.transforms {
+		var err error
+		if val, err = transform(val); err != nil {
+			return val, &InvalidValueError{Name: f.name, Err: err}
+		}
+	}
+
+	return val, nil
+}
+
+var siMultipliers = map[byte]int64{
+	'k': 1e3,
+	'M': 1e6,
+	'G': 1e9,
+	'T': 1e12,
+}
+
+// parseSIInt is strconv.Atoi with additional support for decimal SI
+// suffixes, e.g. 10k or 2M.
+func parseSIInt(s string) (int, error) {
+	if s == "" {
+		return strconv.Atoi(s)
+	}
+
+	mult, ok := siMultipliers[s[len(s)-1]]
 	if !ok {
-		return nil
+		return strconv.Atoi(s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil {
+		return 0, err
+	}
+
+	v := int64(n) * mult
+	if v/mult != int64(n) || int64(int(v)) != v {
+		return 0, fmt.Errorf("%s is out of range", s)
 	}
 
-	return f.setValueFromString(val)
+	return int(v), nil
 }
 
-func (f *Flag[T]) setValueFromDefault() {
-	if f.defaultValueSet {
-		f.setValue(f.defaultValue)
+var extendedDurationUnitRegexp = regexp.MustCompile(`([0-9]*\.?[0-9]+)([dw])`)
+
+// parseExtendedDuration is time.ParseDuration with additional support for
+// days (d) and weeks (w) units. A day is always 24h, DST is not accounted.
+func parseExtendedDuration(s string) (time.Duration, error) {
+	var convErr error
+	s = extendedDurationUnitRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		v, err := strconv.ParseFloat(m[:len(m)-1], 64)
+		if err != nil {
+			convErr = err
+			return m
+		}
+
+		switch m[len(m)-1] {
+		case 'd':
+			v *= 24
+		case 'w':
+			v *= 24 * 7
+		}
+
+		return strconv.FormatFloat(v, 'f', -1, 64) + "h"
+	})
+
+	if convErr != nil {
+		return 0, convErr
 	}
+
+	return time.ParseDuration(s)
 }
 
 func NewBoolFlag(target *bool, name, helpMessage string) *Flag[bool] {
@@ -162,6 +1225,7 @@ func NewBoolFlag(target *bool, name, helpMessage string) *Flag[bool] {
 		name:        name,
 		helpMessage: helpMessage,
 		isBool:      true,
+		typeName:    "bool",
 		parseFunc:   strconv.ParseBool,
 	}
 }
@@ -172,16 +1236,34 @@ func NewDurationFlag(target *time.Duration, name, helpMessage string) *Flag[time
 		name:        name,
 		helpMessage: helpMessage,
 		placeholder: "DURATION",
+		typeName:    "duration",
 		parseFunc:   time.ParseDuration,
 	}
 }
 
+func NewTimeFlag(target *time.Time, name, helpMessage string) *Flag[time.Time] {
+	return &Flag[time.Time]{
+		target:      target,
+		name:        name,
+		helpMessage: helpMessage,
+		placeholder: "TIME",
+		typeName:    "time",
+		parseFunc: func(s string) (time.Time, error) {
+			return time.Parse(time.RFC3339, s)
+		},
+		formatFunc: func(v time.Time) string {
+			return v.Format(time.RFC3339)
+		},
+	}
+}
+
 func NewIntFlag(target *int, name, helpMessage string) *Flag[int] {
 	return &Flag[int]{
 		target:      target,
 		name:        name,
 		helpMessage: helpMessage,
 		placeholder: "INT",
+		typeName:    "int",
 		parseFunc:   strconv.Atoi,
 	}
 }
@@ -192,6 +1274,7 @@ func NewStringFlag(target *string, name, helpMessage string) *Flag[string] {
 		name:        name,
 		helpMessage: helpMessage,
 		placeholder: "STRING",
+		typeName:    "string",
 		parseFunc: func(s string) (string, error) {
 			return s, nil
 		},
@@ -204,18 +1287,144 @@ func NewFloatFlag(target *float64, bitSize int, name, helpMessage string) *Flag[
 		name:        name,
 		helpMessage: helpMessage,
 		placeholder: "FLOAT",
+		typeName:    "float",
 		parseFunc: func(s string) (float64, error) {
 			return strconv.ParseFloat(s, bitSize)
 		},
 	}
 }
 
+func NewStringSliceFlag(target *[]string, name, helpMessage string) *Flag[[]string] {
+	return &Flag[[]string]{
+		target:      target,
+		name:        name,
+		helpMessage: helpMessage,
+		placeholder: "STRING,...",
+		typeName:    "strings",
+		parseFunc: func(s string) ([]string, error) {
+			if s == "" {
+				return nil, nil
+			}
+
+			return strings.Split(s, ","), nil
+		},
+		accumulate: func(a, b []string) []string {
+			return append(a, b...)
+		},
+		formatFunc: func(v []string) string {
+			return strings.Join(v, ",")
+		},
+	}
+}
+
+func NewStringSetFlag(target *[]string, name, helpMessage string) *Flag[[]string] {
+	f := NewStringSliceFlag(target, name, helpMessage)
+	f.parseFunc = func(s string) ([]string, error) {
+		if s == "" {
+			return nil, nil
+		}
+
+		return newUniqueAppender()(nil, strings.Split(s, ",")), nil
+	}
+	f.accumulate = newUniqueAppender()
+
+	return f
+}
+
+// newUniqueAppender returns a func appending values of b missing from a,
+// preserving the first-seen order. The values seen are tracked in a set,
+// which is only rebuilt when a is not the slice returned by the previous
+// call, so that repeated appends stay linear.
+func newUniqueAppender() func(a, b []string) []string {
+	var (
+		seen map[string]struct{}
+		last []string
+	)
+
+	return func(a, b []string) []string {
+		if seen == nil || len(a) != len(last) || (len(a) != 0 && &a[0] != &last[0]) {
+			seen = make(map[string]struct{}, len(a)+len(b))
+			for _, v := range a {
+				seen[v] = struct{}{}
+			}
+		}
+
+		for _, v := range b {
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				a = append(a, v)
+			}
+		}
+
+		last = a
+		return a
+	}
+}
+
+func NewPairsFlag(target *[]Pair, name, helpMessage string) *Flag[[]Pair] {
+	return &Flag[[]Pair]{
+		target:      target,
+		name:        name,
+		helpMessage: helpMessage,
+		placeholder: "KEY=VALUE",
+		typeName:    "pairs",
+		parseFunc: func(s string) ([]Pair, error) {
+			if s == "" {
+				return nil, nil
+			}
+
+			key, value, found := strings.Cut(s, "=")
+			if !found || key == "" {
+				return nil, errors.New("expected key=value")
+			}
+
+			return []Pair{{Key: key, Value: value}}, nil
+		},
+		accumulate: func(a, b []Pair) []Pair {
+			return append(a, b...)
+		},
+		formatFunc: func(v []Pair) string {
+			pairs := make([]string, 0, len(v))
+			for _, pair := range v {
+				pairs = append(pairs, pair.Key+"="+pair.Value)
+			}
+			return strings.Join(pairs, ",")
+		},
+		// values may contain commas, so each pair is a separate occurrence
+		formatArgsFunc: func(v []Pair) []string {
+			args := make([]string, 0, len(v))
+			for _, pair := range v {
+				args = append(args, pair.Key+"="+pair.Value)
+			}
+			return args
+		},
+	}
+}
+
+func NewTCPAddrFlag(target **net.TCPAddr, name, helpMessage string) *Flag[*net.TCPAddr] {
+	return &Flag[*net.TCPAddr]{
+		target:      target,
+		name:        name,
+		helpMessage: helpMessage,
+		placeholder: "ADDR",
+		typeName:    "addr",
+		parseFunc: func(s string) (*net.TCPAddr, error) {
+			if s == "" {
+				return nil, errEmptyString
+			}
+
+			return net.ResolveTCPAddr("tcp", s)
+		},
+	}
+}
+
 func NewURLFlag(target **url.URL, name, helpMessage string) *Flag[*url.URL] {
 	return &Flag[*url.URL]{
 		target:      target,
 		name:        name,
 		helpMessage: helpMessage,
 		placeholder: "URL",
+		typeName:    "url",
 		parseFunc: func(s string) (*url.URL, error) {
 			if s == "" {
 				return nil, errEmptyString
@@ -225,3 +1434,25 @@ func NewURLFlag(target **url.URL, name, helpMessage string) *Flag[*url.URL] {
 		},
 	}
 }
+
+func NewJSONFlag[T any](target *T, name, helpMessage string) *Flag[T] {
+	return &Flag[T]{
+		target:      target,
+		name:        name,
+		helpMessage: helpMessage,
+		placeholder: "JSON",
+		typeName:    "json",
+		parseFunc: func(s string) (T, error) {
+			var val T
+			err := json.Unmarshal([]byte(s), &val)
+			return val, err
+		},
+		formatFunc: func(v T) string {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Sprint(v)
+			}
+			return string(b)
+		},
+	}
+}
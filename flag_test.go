@@ -15,7 +15,12 @@
 package flenv
 
 import (
+	"math"
+	"net"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -64,6 +69,22 @@ func TestNewFlag(t *testing.T) {
 		assert.Equal(t, false, f.isBool)
 	})
 
+	t.Run("string slice", func(t *testing.T) {
+		var v []string
+		f := NewStringSliceFlag(&v, "test-string-slice-flag", "Test string slice flag")
+		assert.Equal(t, "test-string-slice-flag", f.getName())
+		assert.Equal(t, "--test-string-slice-flag=STRING,...", f.getShortDescription())
+		assert.Equal(t, false, f.isBool)
+	})
+
+	t.Run("tcp addr", func(t *testing.T) {
+		var v *net.TCPAddr
+		f := NewTCPAddrFlag(&v, "test-addr-flag", "Test addr flag")
+		assert.Equal(t, "test-addr-flag", f.getName())
+		assert.Equal(t, "--test-addr-flag=ADDR", f.getShortDescription())
+		assert.Equal(t, false, f.isBool)
+	})
+
 	t.Run("url", func(t *testing.T) {
 		var v *url.URL
 		f := NewURLFlag(&v, "test-url-flag", "Test url flag")
@@ -89,6 +110,59 @@ func TestNewURLFlag(t *testing.T) {
 	})
 }
 
+func TestNewTCPAddrFlag(t *testing.T) {
+	t.Run("PortOnly", func(t *testing.T) {
+		var v *net.TCPAddr
+		f := NewTCPAddrFlag(&v, "listen", "Test flag")
+		err := f.setValueFromString(":8080")
+		require.NoError(t, err)
+		assert.Equal(t, 8080, v.Port)
+		assert.Nil(t, v.IP)
+	})
+
+	t.Run("HostPort", func(t *testing.T) {
+		var v *net.TCPAddr
+		f := NewTCPAddrFlag(&v, "listen", "Test flag")
+		err := f.setValueFromString("127.0.0.1:9000")
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:9000", v.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var v *net.TCPAddr
+		f := NewTCPAddrFlag(&v, "listen", "Test flag")
+		err := f.setValueFromString("127.0.0.1")
+		assert.ErrorContains(t, err, "--listen")
+	})
+
+	t.Run("EmptyString", func(t *testing.T) {
+		var v *net.TCPAddr
+		f := NewTCPAddrFlag(&v, "listen", "Test flag")
+		err := f.setValueFromString("")
+		assert.Error(t, err)
+	})
+
+	t.Run("FromEnv", func(t *testing.T) {
+		t.Setenv("LISTEN", "127.0.0.1:9000")
+
+		var v *net.TCPAddr
+		p := New()
+		p.TCPAddr(&v, "listen", "Test flag")
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:9000", v.String())
+	})
+
+	t.Run("FromDefault", func(t *testing.T) {
+		var v *net.TCPAddr
+		p := New()
+		p.TCPAddr(&v, "listen", "Test flag").Default(&net.TCPAddr{Port: 8080})
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, ":8080", v.String())
+	})
+}
+
 func TestFlagLongDescription(t *testing.T) {
 	t.Run("required", func(t *testing.T) {
 		var s string
@@ -109,6 +183,45 @@ func TestFlagEnv(t *testing.T) {
 	assert.Equal(t, "TEST_FLAG", f.envVarName)
 }
 
+func TestFlagEnvPresenceImpliesTrue(t *testing.T) {
+	t.Run("NonBoolPanic", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.EnvPresenceImpliesTrue()
+		})
+	})
+
+	t.Run("SetEmpty", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "")
+
+		var v bool
+		f := NewBoolFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG").EnvPresenceImpliesTrue()
+		err := f.setValueFromEnv(os.LookupEnv)
+		require.NoError(t, err)
+		assert.True(t, v)
+	})
+
+	t.Run("SetFalse", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "false")
+
+		var v bool
+		f := NewBoolFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG").EnvPresenceImpliesTrue()
+		err := f.setValueFromEnv(os.LookupEnv)
+		require.NoError(t, err)
+		assert.True(t, v)
+	})
+
+	t.Run("Unset", func(t *testing.T) {
+		var v bool
+		f := NewBoolFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG_UNSET").EnvPresenceImpliesTrue()
+		err := f.setValueFromEnv(os.LookupEnv)
+		require.NoError(t, err)
+		assert.False(t, v)
+		assert.False(t, f.isSet())
+	})
+}
+
 func TestFlagPlaceholder(t *testing.T) {
 	t.Run("BoolPanic", func(t *testing.T) {
 		var v bool
@@ -203,7 +316,7 @@ func TestFlagSetValue(t *testing.T) {
 
 		var v int
 		f := NewIntFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG")
-		err := f.setValueFromEnv()
+		err := f.setValueFromEnv(os.LookupEnv)
 		require.NoError(t, err)
 		assert.Equal(t, 10, v)
 	})
@@ -211,7 +324,474 @@ func TestFlagSetValue(t *testing.T) {
 	t.Run("FromDefault", func(t *testing.T) {
 		var v int
 		f := NewIntFlag(&v, "test-flag", "Test flag").Default(10)
-		f.setValueFromDefault()
+		err := f.setValueFromDefault(os.LookupEnv)
+		require.NoError(t, err)
 		assert.Equal(t, 10, v)
 	})
 }
+
+func TestFlagEnvIndexed(t *testing.T) {
+	t.Run("NonSlicePanic", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.EnvIndexed()
+		})
+	})
+
+	t.Run("Contiguous", func(t *testing.T) {
+		t.Setenv("TEST_FLAG_0", "foo")
+		t.Setenv("TEST_FLAG_1", "bar")
+		t.Setenv("TEST_FLAG_2", "baz")
+
+		var v []string
+		f := NewStringSliceFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG").EnvIndexed()
+		err := f.setValueFromEnv(os.LookupEnv)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"foo", "bar", "baz"}, v)
+	})
+
+	t.Run("Gap", func(t *testing.T) {
+		t.Setenv("TEST_FLAG_0", "foo")
+		t.Setenv("TEST_FLAG_1", "bar")
+		t.Setenv("TEST_FLAG_3", "baz")
+
+		var v []string
+		f := NewStringSliceFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG").EnvIndexed()
+		err := f.setValueFromEnv(os.LookupEnv)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"foo", "bar"}, v)
+	})
+
+	t.Run("Unset", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "foo")
+
+		v := []string{"default"}
+		f := NewStringSliceFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG").EnvIndexed()
+		err := f.setValueFromEnv(os.LookupEnv)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"default"}, v)
+		assert.False(t, f.isSet())
+	})
+}
+
+func TestFlagAllowExtendedUnits(t *testing.T) {
+	t.Run("NonDurationPanic", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.AllowExtendedUnits()
+		})
+	})
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{value: "30d", expected: 30 * 24 * time.Hour, valid: true},
+		{value: "1w", expected: 7 * 24 * time.Hour, valid: true},
+		{value: "1w3d12h", expected: (7+3)*24*time.Hour + 12*time.Hour, valid: true},
+		{value: "1.5d", expected: 36 * time.Hour, valid: true},
+		{value: "90m", expected: 90 * time.Minute, valid: true},
+		{value: "3y", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v time.Duration
+			f := NewDurationFlag(&v, "test-flag", "Test flag").AllowExtendedUnits()
+			err := f.setValueFromString(tt.value)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestFlagAllowSIUnits(t *testing.T) {
+	t.Run("NonIntPanic", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.AllowSIUnits()
+		})
+	})
+
+	tests := []struct {
+		value    string
+		expected int
+		valid    bool
+	}{
+		{value: "10k", expected: 10000, valid: true},
+		{value: "2M", expected: 2000000, valid: true},
+		{value: "3G", expected: 3000000000, valid: true},
+		{value: "500", expected: 500, valid: true},
+		{value: "-1k", expected: -1000, valid: true},
+		{value: "10x", valid: false},
+		{value: "10Ki", valid: false},
+		{value: "1.5k", valid: false},
+		{value: "k", valid: false},
+		{value: "99999999T", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v int
+			f := NewIntFlag(&v, "test-flag", "Test flag").AllowSIUnits()
+			err := f.setValueFromString(tt.value)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestFlagNegativeDuration(t *testing.T) {
+	t.Run("NonDurationPanic", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.NonNegative()
+		})
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		var v time.Duration
+		f := NewDurationFlag(&v, "offset", "Test flag")
+		err := f.setValueFromString("-5m")
+		require.NoError(t, err)
+		assert.Equal(t, -5*time.Minute, v)
+	})
+
+	t.Run("SpaceForm", func(t *testing.T) {
+		var v time.Duration
+		p := New()
+		p.Duration(&v, "offset", "Test flag")
+
+		errs := p.parse([]string{"--offset", "-5m"})
+		assert.Empty(t, errs)
+		assert.Equal(t, -5*time.Minute, v)
+	})
+
+	t.Run("NonNegative", func(t *testing.T) {
+		var v time.Duration
+		f := NewDurationFlag(&v, "timeout", "Test flag").NonNegative()
+		err := f.setValueFromString("-5m")
+		assert.EqualError(t, err, "invalid value for --timeout: must not be negative")
+
+		err = f.setValueFromString("0s")
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), v)
+	})
+}
+
+func TestFlagParseFunc(t *testing.T) {
+	lower := func(s string) (string, error) {
+		return strings.ToLower(strings.TrimSpace(s)), nil
+	}
+
+	t.Run("NilPanic", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.ParseFunc(nil)
+		})
+	})
+
+	t.Run("Args", func(t *testing.T) {
+		var v string
+		p := New()
+		p.String(&v, "test-flag", "Test flag").ParseFunc(lower)
+
+		errs := p.parse([]string{"--test-flag", "FooBar"})
+		assert.Empty(t, errs)
+		assert.Equal(t, "foobar", v)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", " FooBar ")
+
+		var v string
+		p := New()
+		p.String(&v, "test-flag", "Test flag").ParseFunc(lower)
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.Equal(t, "foobar", v)
+	})
+}
+
+func TestFlagKeyword(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{value: "never", expected: 0, valid: true},
+		{value: "forever", expected: time.Duration(math.MaxInt64), valid: true},
+		{value: "30s", expected: 30 * time.Second, valid: true},
+		{value: "Never", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v time.Duration
+			f := NewDurationFlag(&v, "timeout", "Test flag").
+				Keyword("never", 0).
+				Keyword("forever", math.MaxInt64)
+
+			err := f.setValueFromString(tt.value)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestFlagStringValidators(t *testing.T) {
+	t.Run("NonStringPanic", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.ASCIIOnly()
+		})
+		assert.Panics(t, func() {
+			f.NoControlChars()
+		})
+	})
+
+	t.Run("ControlChar", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").NoControlChars()
+		err := f.setValueFromString("foo\x1b[31mbar")
+		assert.EqualError(t, err, `invalid value for --test-flag: control character '\x1b' at position 3`)
+	})
+
+	t.Run("NonASCII", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").ASCIIOnly()
+		err := f.setValueFromString("naïve")
+		assert.EqualError(t, err, `invalid value for --test-flag: non-ASCII character 'ï' at position 2`)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").ASCIIOnly().NoControlChars()
+		err := f.setValueFromString("naive")
+		require.NoError(t, err)
+		assert.Equal(t, "naive", v)
+	})
+
+	t.Run("FromEnv", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "foo\tbar")
+
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").Env("TEST_FLAG").NoControlChars()
+		err := f.setValueFromEnv(os.LookupEnv)
+		assert.Error(t, err)
+	})
+
+	t.Run("FromDefault", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").Default("naïve").ASCIIOnly()
+		err := f.setValueFromDefault(os.LookupEnv)
+		assert.Error(t, err)
+		assert.Equal(t, "", v)
+	})
+}
+
+func TestFlagStringTransforms(t *testing.T) {
+	t.Run("NonStringPanic", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.TrimSpace()
+		})
+		assert.Panics(t, func() {
+			f.ToLower()
+		})
+		assert.Panics(t, func() {
+			f.Abs()
+		})
+	})
+
+	t.Run("TrimSpace", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").TrimSpace()
+		err := f.setValueFromString("  foo\t")
+		require.NoError(t, err)
+		assert.Equal(t, "foo", v)
+	})
+
+	t.Run("ToLower", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "FooBar")
+
+		var v string
+		p := New()
+		p.String(&v, "test-flag", "Test flag").ToLower()
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.Equal(t, "foobar", v)
+	})
+
+	t.Run("Abs", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+
+		var v string
+		p := New()
+		p.String(&v, "test-flag", "Test flag").Default("data/db").Abs()
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.Equal(t, filepath.Join(wd, "data", "db"), v)
+	})
+
+	t.Run("ChainingOrder", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag").TrimSpace().Abs()
+		err := f.setValueFromString(" /data ")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Clean("/data"), v)
+	})
+}
+
+func TestFlagAllowUnix(t *testing.T) {
+	t.Run("NonTimePanic", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.AllowUnix()
+		})
+		assert.Panics(t, func() {
+			f.AllowUnixMillis()
+		})
+	})
+
+	t.Run("Seconds", func(t *testing.T) {
+		var v time.Time
+		f := NewTimeFlag(&v, "test-flag", "Test flag").AllowUnix()
+
+		err := f.setValueFromString("1700000000")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC), v)
+
+		err = f.setValueFromString("2024-01-02T03:04:05Z")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), v)
+	})
+
+	t.Run("Millis", func(t *testing.T) {
+		var v time.Time
+		f := NewTimeFlag(&v, "test-flag", "Test flag").AllowUnixMillis()
+
+		err := f.setValueFromString("1700000000123")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 20, 123000000, time.UTC), v)
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		var v time.Time
+		f := NewTimeFlag(&v, "test-flag", "Test flag")
+
+		err := f.setValueFromString("1700000000")
+		assert.Error(t, err)
+	})
+}
+
+func TestFlagIntValidators(t *testing.T) {
+	t.Run("NonIntPanic", func(t *testing.T) {
+		var v string
+		f := NewStringFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.Min(1)
+		})
+		assert.Panics(t, func() {
+			f.Max(1)
+		})
+		assert.Panics(t, func() {
+			f.MultipleOf(512)
+		})
+	})
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "4096"},
+		{value: "1000", expected: "invalid value for --block-size: must be a multiple of 512"},
+		{value: "0", expected: "invalid value for --block-size: must be at least 512"},
+		{value: "65536", expected: "invalid value for --block-size: must be at most 8192"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v int
+			f := NewIntFlag(&v, "block-size", "Test flag").Min(512).Max(8192).MultipleOf(512)
+			err := f.setValueFromString(tt.value)
+			if tt.expected != "" {
+				assert.EqualError(t, err, tt.expected)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("FromDefault", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "block-size", "Test flag").Default(1000).MultipleOf(512)
+		err := f.setValueFromDefault(os.LookupEnv)
+		assert.EqualError(t, err, "invalid value for --block-size: must be a multiple of 512")
+	})
+}
+
+func TestFlagLength(t *testing.T) {
+	t.Run("NonStringPanic", func(t *testing.T) {
+		var v int
+		f := NewIntFlag(&v, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.Length(3, 16)
+		})
+	})
+
+	tests := []struct {
+		value    string
+		max      int
+		expected string
+	}{
+		{value: "jo", max: 16, expected: "invalid value for --username: must be 3 to 16 characters"},
+		{value: "john", max: 16},
+		{value: "johnjohnjohnjohnjohn", max: 16, expected: "invalid value for --username: must be 3 to 16 characters"},
+		{value: "żółw", max: 4},
+		{value: "jo", max: -1, expected: "invalid value for --username: must be at least 3 characters"},
+		{value: "johnjohnjohnjohnjohn", max: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v string
+			f := NewStringFlag(&v, "username", "Test flag").Length(3, tt.max)
+			err := f.setValueFromString(tt.value)
+			if tt.expected != "" {
+				assert.EqualError(t, err, tt.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, v)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// FlagGroup registers flags rendered together under a heading in help.
+type FlagGroup struct {
+	parser *Parser
+}
+
+func (p *Parser) Group(name string, registrar func(g *FlagGroup)) {
+	if p.currentGroup != "" {
+		panic("nesting flag groups is not possible")
+	}
+
+	if !slices.Contains(p.groups, name) {
+		p.groups = append(p.groups, name)
+	}
+
+	p.currentGroup = name
+	defer func() {
+		p.currentGroup = ""
+	}()
+
+	registrar(&FlagGroup{parser: p})
+}
+
+func (g *FlagGroup) Bool(target *bool, name, description string) *Flag[bool] {
+	return g.parser.Bool(target, name, description)
+}
+
+func (g *FlagGroup) BoolWithEnvDefault(target *bool, name, description, envDefaultName string) *Flag[bool] {
+	return g.parser.BoolWithEnvDefault(target, name, description, envDefaultName)
+}
+
+func (g *FlagGroup) Duration(target *time.Duration, name, description string) *Flag[time.Duration] {
+	return g.parser.Duration(target, name, description)
+}
+
+func (g *FlagGroup) Time(target *time.Time, name, description string) *Flag[time.Time] {
+	return g.parser.Time(target, name, description)
+}
+
+func (g *FlagGroup) Int(target *int, name, description string) *Flag[int] {
+	return g.parser.Int(target, name, description)
+}
+
+func (g *FlagGroup) String(target *string, name, description string) *Flag[string] {
+	return g.parser.String(target, name, description)
+}
+
+func (g *FlagGroup) Float(target *float64, bitSize int, name, description string) *Flag[float64] {
+	return g.parser.Float(target, bitSize, name, description)
+}
+
+func (g *FlagGroup) StringSlice(target *[]string, name, description string) *Flag[[]string] {
+	return g.parser.StringSlice(target, name, description)
+}
+
+func (g *FlagGroup) StringSet(target *[]string, name, description string) *Flag[[]string] {
+	return g.parser.StringSet(target, name, description)
+}
+
+func (g *FlagGroup) Pairs(target *[]Pair, name, description string) *Flag[[]Pair] {
+	return g.parser.Pairs(target, name, description)
+}
+
+func (g *FlagGroup) TCPAddr(target **net.TCPAddr, name, description string) *Flag[*net.TCPAddr] {
+	return g.parser.TCPAddr(target, name, description)
+}
+
+func (g *FlagGroup) URL(target **url.URL, name, description string) *Flag[*url.URL] {
+	return g.parser.URL(target, name, description)
+}
+
+// groupedFlags splits flags into the ungrouped ones and the ones of each
+// group, in the group registration order.
+func (p *Parser) groupedFlags(flags []flag) ([]flag, [][]flag) {
+	var ungrouped []flag
+	grouped := make([][]flag, len(p.groups))
+
+	for _, flag := range flags {
+		group, ok := p.flagGroups[flag.getName()]
+		if !ok {
+			ungrouped = append(ungrouped, flag)
+			continue
+		}
+
+		i := slices.Index(p.groups, group)
+		grouped[i] = append(grouped[i], flag)
+	}
+
+	return ungrouped, grouped
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserGroup(t *testing.T) {
+	type dbConfig struct {
+		Host     string
+		Port     int
+		User     string
+		Password string
+	}
+
+	newParser := func(db *dbConfig, verbose *bool) *Parser {
+		p := New(WithAppName("test-app"))
+		p.Bool(verbose, "verbose", "Verbose output")
+		p.Group("Database", func(g *FlagGroup) {
+			g.String(&db.Host, "db-host", "Database host").Default("localhost")
+			g.Int(&db.Port, "db-port", "Database port").Default(5432)
+			g.String(&db.User, "db-user", "Database user")
+			g.String(&db.Password, "db-password", "Database password").Hidden()
+		})
+		return p
+	}
+
+	t.Run("Populated", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "s3cr3t")
+
+		var (
+			db      dbConfig
+			verbose bool
+		)
+		p := newParser(&db, &verbose)
+
+		err := p.ParseArgs([]string{"--db-user=admin", "--db-port=6432", "--verbose"})
+		require.NoError(t, err)
+		assert.Equal(t, dbConfig{Host: "localhost", Port: 6432, User: "admin", Password: "s3cr3t"}, db)
+		assert.True(t, verbose)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		var (
+			db      dbConfig
+			verbose bool
+		)
+		p := newParser(&db, &verbose)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+
+		assert.Contains(t, buf.String(), "\n\nFlags:\n"+
+			"  --help     Show help message\n"+
+			"  --verbose  Verbose output [$VERBOSE]\n"+
+			"\n"+
+			"Database:\n"+
+			"  --db-host=STRING  Database host (default: localhost) [$DB_HOST]\n"+
+			"  --db-port=INT     Database port (default: 5432) [$DB_PORT]\n"+
+			"  --db-user=STRING  Database user [$DB_USER]\n")
+	})
+
+	t.Run("NestedPanic", func(t *testing.T) {
+		p := New()
+		assert.Panics(t, func() {
+			p.Group("Outer", func(g *FlagGroup) {
+				p.Group("Inner", func(g *FlagGroup) {})
+			})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var interpolationRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+const (
+	interpolationInProgress = iota + 1
+	interpolationDone
+)
+
+type interpolator struct {
+	flagIndex map[string]flag
+	state     map[string]int
+}
+
+func (p *Parser) interpolateFlags() []error {
+	ip := &interpolator{
+		flagIndex: p.flagIndex,
+		state:     make(map[string]int),
+	}
+
+	var errs []error
+	for _, flag := range p.flags {
+		if _, err := ip.resolve(flag, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+func (ip *interpolator) resolve(f flag, path []string) (string, error) {
+	name := f.getName()
+	path = append(path, "--"+name)
+
+	switch ip.state[name] {
+	case interpolationDone:
+		return f.getValueString(), nil
+	case interpolationInProgress:
+		return "", fmt.Errorf("interpolation cycle: %s", strings.Join(path, " -> "))
+	}
+
+	if !f.isInterpolated() {
+		ip.state[name] = interpolationDone
+		return f.getValueString(), nil
+	}
+
+	ip.state[name] = interpolationInProgress
+	defer func() {
+		ip.state[name] = interpolationDone
+	}()
+
+	var resolveErr error
+	val := interpolationRegexp.ReplaceAllStringFunc(f.getValueString(), func(ref string) string {
+		refName := interpolationRegexp.FindStringSubmatch(ref)[1]
+
+		refFlag := ip.flagIndex[refName]
+		if refFlag == nil {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("unknown flag --%s referenced by --%s", refName, name)
+			}
+			return ref
+		}
+
+		refVal, err := ip.resolve(refFlag, path)
+		if err != nil && resolveErr == nil {
+			resolveErr = err
+		}
+		return refVal
+	})
+
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	if err := f.setInterpolatedValue(val); err != nil {
+		return "", err
+	}
+
+	return f.getValueString(), nil
+}
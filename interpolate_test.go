@@ -0,0 +1,107 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserInterpolate(t *testing.T) {
+	t.Run("NonStringPanic", func(t *testing.T) {
+		var i int
+		p := New()
+		assert.Panics(t, func() {
+			p.Int(&i, "test-flag", "Test flag").Interpolate()
+		})
+	})
+
+	t.Run("SimpleReference", func(t *testing.T) {
+		var dataDir, cacheDir string
+		p := New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Default("${data-dir}/cache").Interpolate()
+
+		err := p.ParseArgs([]string{"--data-dir=/var/lib/app"})
+		require.NoError(t, err)
+		assert.Equal(t, "/var/lib/app/cache", cacheDir)
+	})
+
+	t.Run("ChainedReference", func(t *testing.T) {
+		var port int
+		var addr, url string
+		p := New()
+		p.Int(&port, "port", "Port").Default(8080)
+		p.String(&url, "url", "URL").Default("http://${addr}/").Interpolate()
+		p.String(&addr, "addr", "Address").Default("localhost:${port}").Interpolate()
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "localhost:8080", addr)
+		assert.Equal(t, "http://localhost:8080/", url)
+	})
+
+	t.Run("UnknownReference", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").Default("${nonexistent-flag}").Interpolate()
+
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "unknown flag --nonexistent-flag referenced by --test-flag")
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		var a, b string
+		p := New()
+		p.String(&a, "a", "Flag a").Default("${b}").Interpolate()
+		p.String(&b, "b", "Flag b").Default("${a}").Interpolate()
+
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "interpolation cycle: --a -> --b -> --a")
+	})
+
+	t.Run("ValidateExpandedValue", func(t *testing.T) {
+		var dataDir, cacheDir string
+		p := New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Length(0, 5).Interpolate()
+
+		err := p.ParseArgs([]string{"--data-dir=/d", "--cache-dir=${data-dir}/c"})
+		require.NoError(t, err)
+		assert.Equal(t, "/d/c", cacheDir)
+
+		p = New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Length(0, 5).Interpolate()
+
+		err = p.ParseArgs([]string{"--data-dir=/var/lib", "--cache-dir=${data-dir}/c"})
+		var invalidValueErr *InvalidValueError
+		require.ErrorAs(t, err, &invalidValueErr)
+		assert.Equal(t, "cache-dir", invalidValueErr.Name)
+	})
+
+	t.Run("TransformExpandedValue", func(t *testing.T) {
+		var dataDir, cacheDir string
+		p := New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Default("${data-dir}/Cache").ToLower().Interpolate()
+
+		err := p.ParseArgs([]string{"--data-dir=/Data"})
+		require.NoError(t, err)
+		assert.Equal(t, "/data/cache", cacheDir)
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func (p *Parser) WriteMarkdown(w io.Writer) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintln(b, "| Flag | Env Var | Type | Default | Required | Description |")
+	fmt.Fprintln(b, "| --- | --- | --- | --- | --- | --- |")
+
+	for _, flag := range p.visibleFlags() {
+		envVar := ""
+		if envVarName := flag.getEnvVarName(); envVarName != "" {
+			envVar = markdownCode(envVarName)
+		}
+
+		defaultValue := ""
+		if v, ok := flag.getDefaultValueString(); ok {
+			defaultValue = markdownCode(v)
+		}
+
+		required := "no"
+		if flag.isRequired() {
+			required = "yes"
+		}
+
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCode("--"+flag.getName()),
+			envVar,
+			flag.getTypeName(),
+			defaultValue,
+			required,
+			markdownEscape(flag.getHelpMessage()),
+		)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCode(s string) string {
+	return "`" + markdownEscape(s) + "`"
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserWriteMarkdown(t *testing.T) {
+	var (
+		d time.Duration
+		i int
+		s string
+	)
+
+	p := New()
+	p.Duration(&d, "timeout", "Request timeout").Default(30 * time.Second)
+	p.Int(&i, "port", "Port to listen on").Required()
+	p.String(&s, "secret", "Hidden flag").Hidden()
+
+	buf := bytes.NewBuffer(nil)
+	err := p.WriteMarkdown(buf)
+	require.NoError(t, err)
+
+	const expected = "| Flag | Env Var | Type | Default | Required | Description |\n" +
+		"| --- | --- | --- | --- | --- | --- |\n" +
+		"| `--help` |  | bool |  | no | Show help message |\n" +
+		"| `--port` | `PORT` | int |  | yes | Port to listen on |\n" +
+		"| `--timeout` | `TIMEOUT` | duration | `30s` | no | Request timeout |\n"
+
+	assert.Equal(t, expected, buf.String())
+}
@@ -14,6 +14,13 @@
 
 package flenv
 
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
 type Option func(*Parser)
 
 func WithEnvVarPrefix(prefix string) Option {
@@ -28,12 +35,212 @@ func WithEnvVarFormatter(f func(string) string) Option {
 	}
 }
 
+func WithEnvVarPrefixFormatter(f func(string) string) Option {
+	return func(p *Parser) {
+		p.prefixFormatter = f
+	}
+}
+
+func WithSecretResolver(r SecretResolver) Option {
+	return func(p *Parser) {
+		p.secretResolver = r
+	}
+}
+
+func WithHostResolver(r HostResolver) Option {
+	return func(p *Parser) {
+		p.hostResolver = r
+	}
+}
+
+func WithStdin(r io.Reader) Option {
+	return func(p *Parser) {
+		p.stdin = r
+	}
+}
+
 func WithoutAutoEnv() Option {
 	return func(p *Parser) {
 		p.autoEnv = false
 	}
 }
 
+func WithEnvHelpSection() Option {
+	return func(p *Parser) {
+		p.envHelpSection = true
+	}
+}
+
+func WithBoolWords(trueWords, falseWords []string) Option {
+	return func(p *Parser) {
+		if p.boolWords == nil {
+			p.boolWords = make(map[string]bool)
+		}
+
+		for _, word := range trueWords {
+			p.boolWords[strings.ToLower(word)] = true
+		}
+		for _, word := range falseWords {
+			p.boolWords[strings.ToLower(word)] = false
+		}
+	}
+}
+
+func WithJSONErrors() Option {
+	return func(p *Parser) {
+		p.jsonErrors = true
+	}
+}
+
+func WithAtomicParse() Option {
+	return func(p *Parser) {
+		p.atomicParse = true
+	}
+}
+
+func WithWarnRedundantDefaults() Option {
+	return func(p *Parser) {
+		p.warnRedundantDefaults = true
+	}
+}
+
+func WithTiming() Option {
+	return func(p *Parser) {
+		p.timings = make(map[string]time.Duration)
+	}
+}
+
+func WithRequiredLabel(label string) Option {
+	return func(p *Parser) {
+		p.requiredLabel = label
+	}
+}
+
+func WithDefaultLabelFormat(format string) Option {
+	return func(p *Parser) {
+		p.defaultLabelFormat = format
+	}
+}
+
+func WithTypeHints() Option {
+	return func(p *Parser) {
+		p.typeHints = true
+	}
+}
+
+func WithDefaultsInUsage() Option {
+	return func(p *Parser) {
+		p.defaultsInUsage = true
+	}
+}
+
+func WithHelpPadding(minwidth, tabwidth, padding int, padchar byte) Option {
+	return func(p *Parser) {
+		p.helpMinWidth = minwidth
+		p.helpTabWidth = tabwidth
+		p.helpPadding = padding
+		p.helpPadChar = padchar
+	}
+}
+
+func WithAbbreviations() Option {
+	return func(p *Parser) {
+		p.abbreviations = true
+	}
+}
+
+func WithNameNormalization() Option {
+	return func(p *Parser) {
+		p.normalizeName = true
+	}
+}
+
+func WithIgnoreUnknownFlags() Option {
+	return func(p *Parser) {
+		p.ignoreUnknown = true
+	}
+}
+
+func WithPassThroughOnUnknown() Option {
+	return func(p *Parser) {
+		p.passThrough = true
+	}
+}
+
+func WithMaxArgs(n int) Option {
+	return func(p *Parser) {
+		p.maxArgs = n
+	}
+}
+
+func WithSkipArgs(n int) Option {
+	return func(p *Parser) {
+		p.skipArgs = n
+	}
+}
+
+func WithEchoArgsOnError() Option {
+	return func(p *Parser) {
+		p.echoArgsOnError = true
+	}
+}
+
+func WithLenientAssignment() Option {
+	return func(p *Parser) {
+		p.lenientAssignment = true
+	}
+}
+
+func WithSlashFlags() Option {
+	return func(p *Parser) {
+		p.slashFlags = true
+	}
+}
+
+func WithEnvSnapshot() Option {
+	return func(p *Parser) {
+		environ := os.Environ()
+
+		p.envSnapshot = make(map[string]string, len(environ))
+		for _, kv := range environ {
+			if name, val, ok := strings.Cut(kv, "="); ok {
+				p.envSnapshot[name] = val
+			}
+		}
+	}
+}
+
+func WithUnknownArgHandler(fn func(arg string) error) Option {
+	return func(p *Parser) {
+		p.unknownArgHandler = fn
+	}
+}
+
+func WithStrictEnv() Option {
+	return func(p *Parser) {
+		p.strictEnv = true
+	}
+}
+
+func WithStrictMode() Option {
+	return func(p *Parser) {
+		p.strictEnv = true
+		p.strictMode = true
+	}
+}
+
+func WithStrictBoolEnv() Option {
+	return func(p *Parser) {
+		p.strictBoolEnv = true
+	}
+}
+
+func WithIgnoredEnvVars(patterns ...string) Option {
+	return func(p *Parser) {
+		p.ignoredEnvVars = append(p.ignoredEnvVars, patterns...)
+	}
+}
+
 func WithHelpFlagName(name string) Option {
 	return func(p *Parser) {
 		p.helpFlagName = name
@@ -52,6 +259,12 @@ func WithAppVersion(version string) Option {
 	}
 }
 
+func WithMetadata(meta Metadata) Option {
+	return func(p *Parser) {
+		p.metadata = meta
+	}
+}
+
 func WithAppName(name string) Option {
 	return func(p *Parser) {
 		p.appName = name
@@ -15,60 +15,224 @@
 package flenv
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+var (
+	ErrHelp    = errors.New("help requested")
+	ErrVersion = errors.New("version requested")
+	ErrInfo    = errors.New("info requested")
+)
+
+type SecretResolver interface {
+	Resolve(key string) (string, error)
+}
+
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
 type flag interface {
 	isRequired() bool
+	isRequiredByEnv(func(string) (string, bool)) bool
+	isHidden() bool
+	isObsolete() bool
+	isRepeatable() bool
+	acceptsEmpty() bool
 	isSet() bool
+	isInterpolated() bool
+	isAbbreviable() bool
+	isSensitive() bool
+	getSource() valueSource
 	getName() string
+	getShort() rune
+	bind(*Parser)
+	getHelpMessage() string
+	getTypeName() string
+	getPlaceholder() string
+	isBoolFlag() bool
+	getEnvVarName() string
+	getSecretKey() string
+	getEnvVarNames() []string
+	matchesEnvVar(string) bool
 	getLongDescription() string
+	getDetailedDescription() string
 	getShortDescription() string
-	setValueFromDefault()
-	setValueFromEnv() error
+	setValueFromDefault(func(string) (string, bool)) error
+	setValueFromDefaultFunc() error
+	setValueFromEnv(func(string) (string, bool)) error
+	setValueFromSecret(SecretResolver) error
 	setValueFromString(string) error
+	parseValue(string, valueSource) error
+	getValueString() string
+	getDisplayValue() string
+	getArgValues() []string
+	getDefaultValueString() (string, bool)
+	equalsDefault() bool
+	setInterpolatedValue(string) error
+	resetInterpolation()
+	snapshot() func()
+}
+
+type Metadata struct {
+	Name        string
+	Version     string
+	Commit      string
+	BuildDate   string
+	Description string
+}
+
+type infoFlag struct {
+	name    string
+	content string
+	called  bool
+}
+
+type extraFlags struct {
+	prefix string
+	target *map[string]string
 }
 
 type Parser struct {
 	envVarFormatter func(string) string
 	envVarPrefix    string
+	prefixFormatter func(string) string
 	autoEnv         bool
+	envSnapshot     map[string]string
+	dotEnv          map[string]string
+	strictEnv       bool
+	strictBoolEnv   bool
+	strictMode      bool
+	ignoredEnvVars  []string
 
-	helpFlagName string
+	secretResolver SecretResolver
+	stdin          io.Reader
+	hostResolver   HostResolver
+
+	helpFlagName    string
+	envHelpSection  bool
+	defaultsInUsage bool
+
+	helpMinWidth int
+	helpTabWidth int
+	helpPadding  int
+	helpPadChar  byte
+	typeHints    bool
+
+	requiredLabel      string
+	defaultLabelFormat string
 
 	appName            string
 	appVersion         string
 	appVersionFlagName string
+	metadata           Metadata
+
+	boolWords map[string]bool
+
+	jsonErrors    bool
+	atomicParse   bool
+	slashFlags    bool
+	abbreviations bool
+	normalizeName bool
+	ignoreUnknown bool
+	passThrough   bool
+
+	lenientAssignment     bool
+	maxArgs               int
+	skipArgs              int
+	echoArgsOnError       bool
+	warnRedundantDefaults bool
 
 	helpCalled    bool
+	helpTopic     string
 	versionCalled bool
 
-	flags     []flag
-	flagIndex map[string]flag
+	flags      []flag
+	flagIndex  map[string]flag
+	args       []flag
+	argIndex   int
+	argCount   int
+	argRange   *[2]int
+	shortIndex map[rune]flag
+
+	configLoaders     map[string]func(io.Reader) error
+	layeredValues     map[string]layeredValue
+	extraFlags        []extraFlags
+	infoFlags         []*infoFlag
+	profiles          map[string]map[string]string
+	profileFlagName   string
+	restArgs          *[]string
+	argsSeen          map[string]bool
+	passThroughArgs   []string
+	unknownArgHandler func(string) error
+
+	sameSourceGroups [][]string
+	groups           []string
+	options          []Option
+	parent           *Parser
+	commands         []*command
+	selectedCommand  *command
+	commandArgs      []string
+	flagGroups       map[string]string
+	currentGroup     string
+	rules            []rule
+
+	ctx      context.Context
+	onParsed []func(*Parser)
+	parsed   bool
+	warnings []string
+	timings  map[string]time.Duration
 }
 
 func New(opts ...Option) *Parser {
 	p := &Parser{
-		flagIndex: make(map[string]flag),
+		flagIndex:  make(map[string]flag),
+		shortIndex: make(map[rune]flag),
 		envVarFormatter: func(s string) string {
 			return strings.ReplaceAll(strings.ToUpper(s), "-", "_")
 		},
 		autoEnv:            true,
 		helpFlagName:       "help",
+		helpPadding:        2,
+		helpPadChar:        ' ',
+		requiredLabel:      "(required)",
+		defaultLabelFormat: "(default: %s)",
 		appVersionFlagName: "version",
 	}
 
+	p.options = opts
 	for _, opt := range opts {
 		opt(p)
 	}
 
+	// individual options win over the metadata regardless of their order
+	if p.appName == "" {
+		p.appName = p.metadata.Name
+	}
+	if p.appVersion == "" {
+		p.appVersion = p.metadata.Version
+	}
+
+	if p.prefixFormatter != nil {
+		p.envVarPrefix = p.prefixFormatter(p.envVarPrefix)
+	}
+
 	helpFlag := NewBoolFlag(&p.helpCalled, p.helpFlagName, "Show help message")
 	p.registerFlag(p.helpFlagName, helpFlag)
 
@@ -84,6 +248,10 @@ func (p *Parser) Bool(target *bool, name, description string) *Flag[bool] {
 	f := NewBoolFlag(target, name, description)
 	p.registerFlag(name, f)
 
+	if len(p.boolWords) != 0 {
+		f.parseFunc = p.parseBool
+	}
+
 	if p.autoEnv {
 		envVarName := p.envVarPrefix + p.envVarFormatter(name)
 		f = f.Env(envVarName)
@@ -92,6 +260,12 @@ func (p *Parser) Bool(target *bool, name, description string) *Flag[bool] {
 	return f
 }
 
+func (p *Parser) BoolWithEnvDefault(target *bool, name, description, envDefaultName string) *Flag[bool] {
+	f := p.Bool(target, name, description)
+	f.defaultEnvVarName = envDefaultName
+	return f
+}
+
 func (p *Parser) Duration(target *time.Duration, name, description string) *Flag[time.Duration] {
 	f := NewDurationFlag(target, name, description)
 	p.registerFlag(name, f)
@@ -104,6 +278,18 @@ func (p *Parser) Duration(target *time.Duration, name, description string) *Flag
 	return f
 }
 
+func (p *Parser) Time(target *time.Time, name, description string) *Flag[time.Time] {
+	f := NewTimeFlag(target, name, description)
+	p.registerFlag(name, f)
+
+	if p.autoEnv {
+		envVarName := p.envVarPrefix + p.envVarFormatter(name)
+		f = f.Env(envVarName)
+	}
+
+	return f
+}
+
 func (p *Parser) Int(target *int, name, description string) *Flag[int] {
 	f := NewIntFlag(target, name, description)
 	p.registerFlag(name, f)
@@ -140,8 +326,8 @@ func (p *Parser) Float(target *float64, bitSize int, name, description string) *
 	return f
 }
 
-func (p *Parser) URL(target **url.URL, name, description string) *Flag[*url.URL] {
-	f := NewURLFlag(target, name, description)
+func (p *Parser) StringSlice(target *[]string, name, description string) *Flag[[]string] {
+	f := NewStringSliceFlag(target, name, description)
 	p.registerFlag(name, f)
 
 	if p.autoEnv {
@@ -152,150 +338,1542 @@ func (p *Parser) URL(target **url.URL, name, description string) *Flag[*url.URL]
 	return f
 }
 
-func (p *Parser) Parse() {
-	if errs := p.parse(os.Args[1:]); len(errs) != 0 {
-		p.printErrs(os.Stderr, errs)
-		os.Exit(1)
+func (p *Parser) StringSet(target *[]string, name, description string) *Flag[[]string] {
+	f := NewStringSetFlag(target, name, description)
+	p.registerFlag(name, f)
+
+	if p.autoEnv {
+		envVarName := p.envVarPrefix + p.envVarFormatter(name)
+		f = f.Env(envVarName)
 	}
 
-	if p.helpCalled {
-		p.printHelp(os.Stdout)
-		os.Exit(0)
+	return f
+}
+
+func (p *Parser) Pairs(target *[]Pair, name, description string) *Flag[[]Pair] {
+	f := NewPairsFlag(target, name, description)
+	p.registerFlag(name, f)
+
+	if p.autoEnv {
+		envVarName := p.envVarPrefix + p.envVarFormatter(name)
+		f = f.Env(envVarName)
 	}
 
-	if p.versionCalled {
-		p.printVersion(os.Stdout)
-		os.Exit(0)
+	return f
+}
+
+func (p *Parser) TCPAddr(target **net.TCPAddr, name, description string) *Flag[*net.TCPAddr] {
+	f := NewTCPAddrFlag(target, name, description)
+	p.registerFlag(name, f)
+
+	if p.autoEnv {
+		envVarName := p.envVarPrefix + p.envVarFormatter(name)
+		f = f.Env(envVarName)
 	}
 
-	if errs := p.checkRequiredFlags(); len(errs) != 0 {
-		p.printErrs(os.Stderr, errs)
-		os.Exit(1)
+	return f
+}
+
+func (p *Parser) URL(target **url.URL, name, description string) *Flag[*url.URL] {
+	f := NewURLFlag(target, name, description)
+	p.registerFlag(name, f)
+
+	if p.autoEnv {
+		envVarName := p.envVarPrefix + p.envVarFormatter(name)
+		f = f.Env(envVarName)
 	}
+
+	return f
 }
 
-func (p *Parser) printHelp(w io.Writer) {
-	slices.SortStableFunc(p.flags, func(a, b flag) int {
-		return strings.Compare(a.getName(), b.getName())
-	})
+// JSONFlag registers a flag whose value is a JSON document unmarshaled into
+// the target. It is a function rather than a Parser method since Go methods
+// cannot have type parameters.
+func JSONFlag[T any](p *Parser, target *T, name, description string) *Flag[T] {
+	f := NewJSONFlag(target, name, description)
+	p.registerFlag(name, f)
 
-	appName := p.appName
-	if appName == "" {
-		appName = os.Args[0]
+	if p.autoEnv {
+		envVarName := p.envVarPrefix + p.envVarFormatter(name)
+		f = f.Env(envVarName)
 	}
 
-	fmt.Fprintf(w, "Usage: %s", appName)
-	for _, flag := range p.flags {
-		if flag.isRequired() {
-			fmt.Fprintf(w, " %s", flag.getShortDescription())
+	return f
+}
+
+func (p *Parser) InfoFlag(name, description, content string) {
+	info := &infoFlag{name: name, content: content}
+	f := NewBoolFlag(&info.called, name, description)
+	p.registerFlag(name, f)
+	p.infoFlags = append(p.infoFlags, info)
+}
+
+func (p *Parser) ConfigFileFlag(name string, loader func(io.Reader) error) *Flag[string] {
+	var path string
+	f := NewStringFlag(&path, name, "Load flag values from the config file").Placeholder("FILE")
+	p.registerFlag(name, f)
+
+	if p.configLoaders == nil {
+		p.configLoaders = make(map[string]func(io.Reader) error)
+	}
+	p.configLoaders[name] = loader
+
+	return f
+}
+
+func (p *Parser) Profile(name string, settings map[string]string) {
+	if p.profiles == nil {
+		var profile string
+		p.profileFlagName = "profile"
+		p.registerFlag(p.profileFlagName, NewStringFlag(&profile, p.profileFlagName, "Apply a named preset of flag values"))
+		p.profiles = make(map[string]map[string]string)
+	}
+
+	if _, ok := p.profiles[name]; ok {
+		panic(fmt.Sprintf("profile %s is already registered", name))
+	}
+
+	p.profiles[name] = settings
+}
+
+func (p *Parser) applyProfile(name string) error {
+	settings, ok := p.profiles[name]
+	if !ok {
+		return &InvalidValueError{
+			Name: p.profileFlagName,
+			Err:  fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(sortedKeys(p.profiles), ", ")),
 		}
 	}
-	for _, flag := range p.flags {
-		if !flag.isRequired() {
-			fmt.Fprintf(w, " [%s]", flag.getShortDescription())
+
+	var errs []error
+	for _, flagName := range sortedKeys(settings) {
+		f, err := p.lookupFlag(flagName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if f.getSource() == sourceArgs {
+			// explicit flags win regardless of their position
+			continue
+		}
+
+		if err := f.parseValue(settings[flagName], sourceProfile); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	fmt.Fprint(w, "\n\n")
-	fmt.Fprintln(w, "Flags:")
+	return errors.Join(errs...)
+}
 
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
-	for _, flag := range p.flags {
-		fmt.Fprintln(tw, flag.getLongDescription())
+func (p *Parser) loadConfigFile(name, path string, loader func(io.Reader) error) error {
+	defer p.recordTiming("config:"+name, time.Now())
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("loading config file: %w", err)
 	}
-	tw.Flush()
+	defer file.Close()
+
+	if err := loader(file); err != nil {
+		return fmt.Errorf("loading config file %s: %w", path, err)
+	}
+
+	return nil
 }
 
-func (p *Parser) printVersion(w io.Writer) {
-	fmt.Fprintln(w, p.appVersion)
+func (p *Parser) ExtraFlags(prefix string, target *map[string]string) {
+	p.extraFlags = append(p.extraFlags, extraFlags{prefix: prefix, target: target})
 }
 
-func (p *Parser) printErrs(w io.Writer, errs []error) {
-	for _, err := range errs {
-		fmt.Fprintln(w, err)
+func (p *Parser) lookupExtraFlags(name string) *extraFlags {
+	for i, extra := range p.extraFlags {
+		if strings.HasPrefix(name, extra.prefix) {
+			return &p.extraFlags[i]
+		}
 	}
-	fmt.Fprintf(w, "\nUse '--%s' flag for more info.\n", p.helpFlagName)
+	return nil
 }
 
-func (p *Parser) registerFlag(name string, f flag) {
-	if _, ok := p.flagIndex[name]; ok {
-		panic(fmt.Sprintf("flag with name %s is already registered", name))
+func (p *Parser) setExtraFlag(extra *extraFlags, name, value string) error {
+	key := strings.TrimPrefix(name, extra.prefix)
+	if key == "" {
+		// --set key=value
+		var found bool
+		if key, value, found = strings.Cut(value, "="); !found || key == "" {
+			return &InvalidValueError{Name: name, Err: errors.New("expected key=value")}
+		}
 	}
 
-	p.flags = append(p.flags, f)
-	p.flagIndex[name] = f
+	if *extra.target == nil {
+		*extra.target = make(map[string]string)
+	}
+	(*extra.target)[key] = value
+
+	return nil
 }
 
-func (p *Parser) set(name, value string) error {
-	if f := p.flagIndex[name]; f != nil {
-		return f.setValueFromString(value)
+func (p *Parser) RestArgs(target *[]string) {
+	p.restArgs = target
+}
+
+func (p *Parser) PassThrough() []string {
+	return p.passThroughArgs
+}
+
+func (p *Parser) SameSource(names ...string) {
+	for _, name := range names {
+		if _, ok := p.flagIndex[name]; !ok {
+			panic(fmt.Sprintf("flag with name %s is not registered", name))
+		}
 	}
 
-	return fmt.Errorf("unknown flag: --%s", name)
+	p.sameSourceGroups = append(p.sameSourceGroups, names)
 }
 
-func (p *Parser) parse(args []string) []error {
-	var parseErrs []error
+func (p *Parser) ApplyMap(m map[string]string) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
 
-	for _, v := range p.flagIndex {
-		v.setValueFromDefault()
-		if err := v.setValueFromEnv(); err != nil {
-			parseErrs = append(parseErrs, err)
+	var errs []error
+	for _, name := range names {
+		if err := p.set(name, m[name]); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	for len(args) > 0 {
-		arg := args[0]
-		args = args[1:]
+	return errors.Join(errs...)
+}
 
-		if !strings.HasPrefix(arg, "--") {
-			parseErrs = append(parseErrs, fmt.Errorf("unexpected argument: %s", arg))
-			return parseErrs
+func (p *Parser) ApplyOrdered(pairs [][2]string) error {
+	var errs []error
+	for _, pair := range pairs {
+		if err := p.set(pair[0], pair[1]); err != nil {
+			errs = append(errs, err)
 		}
+	}
 
-		arg = strings.TrimPrefix(arg, "--")
+	return errors.Join(errs...)
+}
 
-		if arg == "" {
-			// end of flags
-			if len(args) != 0 {
-				parseErrs = append(parseErrs, fmt.Errorf("unexpected arguments: %s", strings.Join(args, " ")))
-				return parseErrs
-			}
-			break
-		}
+func (p *Parser) ReloadEnv() []error {
+	var (
+		errs         []error
+		restoreFuncs = make([]func(), 0, len(p.flags))
+	)
 
-		if equalsIdx := strings.Index(arg, "="); equalsIdx != -1 {
-			// --key=value
-			if err := p.set(arg[:equalsIdx], arg[equalsIdx+1:]); err != nil {
-				parseErrs = append(parseErrs, err)
-			}
+	for _, flag := range p.flags {
+		restoreFuncs = append(restoreFuncs, flag.snapshot())
+
+		// command line values are immutable, as are values that never
+		// came from an envvar in the first place
+		if flag.getEnvVarName() == "" || flag.getSource() > sourceEnv {
 			continue
 		}
 
-		if len(args) == 0 || strings.HasPrefix(args[0], "--") {
-			// --key (boolean flag)
-			if err := p.set(arg, "true"); err != nil {
-				parseErrs = append(parseErrs, err)
-			}
-			continue
+		if err := flag.setValueFromEnv(p.lookupEnv); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		// the reloaded values may be referenced by other flags as well
+		for _, flag := range p.flags {
+			flag.resetInterpolation()
 		}
+		errs = p.interpolateFlags()
+	}
 
-		// --key value
-		if err := p.set(arg, args[0]); err != nil {
-			parseErrs = append(parseErrs, err)
+	if len(errs) != 0 {
+		for _, restore := range restoreFuncs {
+			restore()
 		}
-		args = args[1:]
 	}
 
-	return parseErrs
+	return errs
 }
 
-func (p *Parser) checkRequiredFlags() []error {
-	var checkErrs []error
+func (p *Parser) MustBeParsed() {
+	if !p.parsed {
+		panic("flags are accessed before a successful parse")
+	}
+}
 
-	for _, flag := range p.flags {
-		if flag.isRequired() && !flag.isSet() {
-			checkErrs = append(checkErrs, fmt.Errorf("missing required flag: --%s", flag.getName()))
+func (p *Parser) OnParsed(fn func(*Parser)) {
+	p.onParsed = append(p.onParsed, fn)
+}
+
+func (p *Parser) parseBool(s string) (bool, error) {
+	if v, ok := p.boolWords[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	return strconv.ParseBool(s)
+}
+
+func (p *Parser) lookupEnv(name string) (string, bool) {
+	if val, ok := p.lookupProcessEnv(name); ok {
+		return val, true
+	}
+
+	if val, ok := p.dotEnv[name]; ok {
+		return val, true
+	}
+
+	if p.parent != nil {
+		// .env files loaded by the parent command apply to subcommands
+		return p.parent.lookupEnv(name)
+	}
+
+	return "", false
+}
+
+func (p *Parser) lookupProcessEnv(name string) (string, bool) {
+	if p.envSnapshot != nil {
+		val, ok := p.envSnapshot[name]
+		return val, ok
+	}
+
+	return os.LookupEnv(name)
+}
+
+func (p *Parser) Parse() {
+	args := os.Args[1:]
+	if p.skipArgs >= len(args) {
+		args = nil
+	} else if p.skipArgs > 0 {
+		// e.g. a subcommand name left by an outer dispatcher
+		args = args[p.skipArgs:]
+	}
+
+	switch errs := p.run(args); {
+	case len(errs) == 0:
+		for _, warning := range p.warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+	case errs[0] == ErrHelp:
+		p.WriteHelp(os.Stdout)
+		os.Exit(0)
+	case errs[0] == ErrVersion:
+		p.WriteVersion(os.Stdout)
+		os.Exit(0)
+	case errors.Is(errs[0], ErrInfo):
+		p.printInfo(os.Stdout, errs[0])
+		os.Exit(0)
+	default:
+		p.printErrs(os.Stderr, errs)
+		os.Exit(1)
+	}
+}
+
+func (p *Parser) ParseArgs(args []string) error {
+	return p.ParseArgsContext(context.Background(), args)
+}
+
+// ParseArgsContext is ParseArgs passing ctx to the I/O done while parsing,
+// e.g. the host lookups of .RequireResolvableHost().
+func (p *Parser) ParseArgsContext(ctx context.Context, args []string) error {
+	p.ctx = ctx
+	defer func() { p.ctx = nil }()
+
+	return errors.Join(p.run(args)...)
+}
+
+// context returns the context of the ongoing parse, which is shared by the
+// subcommands.
+func (p *Parser) context() context.Context {
+	for parser := p; parser != nil; parser = parser.parent {
+		if parser.ctx != nil {
+			return parser.ctx
+		}
+	}
+
+	return context.Background()
+}
+
+func (p *Parser) ParseString(cmdline string) error {
+	args, err := splitCommandLine(cmdline)
+	if err != nil {
+		return fmt.Errorf("splitting command line: %w", err)
+	}
+
+	return p.ParseArgs(args)
+}
+
+func (p *Parser) run(args []string) (errs []error) {
+	if p.echoArgsOnError {
+		defer func() {
+			if len(errs) != 0 && errs[0] != ErrHelp && errs[0] != ErrVersion && !errors.Is(errs[0], ErrInfo) {
+				errs = append(errs, fmt.Errorf("provided args: %s", strings.Join(p.sanitizeArgs(args), " ")))
+			}
+		}()
+	}
+
+	if p.maxArgs > 0 && len(args) > p.maxArgs {
+		// checked before touching any targets to guard against abuse
+		return []error{fmt.Errorf("too many arguments: got %d, at most %d allowed", len(args), p.maxArgs)}
+	}
+
+	if p.atomicParse {
+		restore := p.snapshot()
+		defer func() {
+			if len(errs) != 0 {
+				restore()
+			}
+		}()
+	}
+
+	if errs := p.process(args); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.check(); len(errs) != 0 {
+		return errs
+	}
+
+	p.finish()
+	return nil
+}
+
+// snapshot captures the targets of the parser and its subcommands, returning
+// a func restoring them.
+func (p *Parser) snapshot() func() {
+	restoreFuncs := make([]func(), 0, len(p.flags)+len(p.args)+len(p.commands))
+	for _, flag := range append(slices.Clone(p.flags), p.args...) {
+		restoreFuncs = append(restoreFuncs, flag.snapshot())
+	}
+	for _, cmd := range p.commands {
+		restoreFuncs = append(restoreFuncs, cmd.parser.snapshot())
+	}
+	if target := p.restArgs; target != nil {
+		rest := *target
+		restoreFuncs = append(restoreFuncs, func() { *target = rest })
+	}
+	for _, extra := range p.extraFlags {
+		target, values := extra.target, *extra.target
+		if values != nil {
+			// extra flags are set in place
+			values = maps.Clone(values)
+		}
+		restoreFuncs = append(restoreFuncs, func() { *target = values })
+	}
+
+	return func() {
+		for _, restore := range restoreFuncs {
+			restore()
+		}
+	}
+}
+
+// process sets the values of the parser's flags and then of the selected
+// subcommand's ones.
+func (p *Parser) process(args []string) []error {
+	if errs := p.parse(args); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.applySecrets(); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.applyDefaultFuncs(); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.checkUnknownEnv(); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.interpolateFlags(); len(errs) != 0 {
+		return errs
+	}
+
+	if p.helpCalled {
+		return []error{ErrHelp}
+	}
+
+	if p.versionCalled {
+		return []error{ErrVersion}
+	}
+
+	for _, info := range p.infoFlags {
+		if info.called {
+			return []error{&InfoRequestedError{Name: info.name, Content: info.content}}
+		}
+	}
+
+	if p.selectedCommand != nil {
+		return p.selectedCommand.parser.process(p.commandArgs)
+	}
+
+	return nil
+}
+
+// check validates the values of the parser and then of the selected
+// subcommand, so the subcommand is only finished once all of the parent
+// commands are valid.
+func (p *Parser) check() []error {
+	if errs := p.checkRequiredFlags(); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.checkRequiredArgs(); len(errs) != 0 {
+		return errs
+	}
+
+	if err := p.checkPositionalRange(); err != nil {
+		return []error{err}
+	}
+
+	if errs := p.checkSameSource(); len(errs) != 0 {
+		return errs
+	}
+
+	if errs := p.checkRules(); len(errs) != 0 {
+		return errs
+	}
+
+	if p.selectedCommand != nil {
+		return p.selectedCommand.parser.check()
+	}
+
+	return nil
+}
+
+// finish marks the parser and the selected subcommand as parsed, running
+// their hooks.
+func (p *Parser) finish() {
+	p.warnings = p.checkRedundantDefaults()
+	p.parsed = true
+
+	for _, fn := range p.onParsed {
+		fn(p)
+	}
+
+	if p.selectedCommand != nil {
+		p.selectedCommand.parser.finish()
+	}
+}
+
+func (p *Parser) WriteHelp(w io.Writer) {
+	p.helpParser().printHelp(w)
+}
+
+func (p *Parser) printHelp(w io.Writer) {
+	if p.helpTopic != "" {
+		if f := p.flagIndex[p.helpTopic]; f != nil {
+			fmt.Fprint(w, f.getDetailedDescription())
+			return
+		}
+
+		fmt.Fprintf(w, "Unknown flag: --%s, showing all flags.\n\n", p.helpTopic)
+	}
+
+	flags := p.visibleFlags()
+
+	fmt.Fprintf(w, "Usage: %s", p.getAppName())
+	for _, flag := range flags {
+		if flag.isRequired() {
+			fmt.Fprintf(w, " %s", flag.getShortDescription())
+		}
+	}
+	for _, flag := range flags {
+		if flag.isRequired() || flag.isObsolete() {
+			continue
+		}
+
+		if defaultValue, ok := flag.getDefaultValueString(); ok && p.defaultsInUsage {
+			fmt.Fprintf(w, " [%s (%s)]", flag.getShortDescription(), defaultValue)
+		} else {
+			fmt.Fprintf(w, " [%s]", flag.getShortDescription())
+		}
+	}
+	p.printArgsUsage(w)
+	if p.restArgs != nil {
+		fmt.Fprint(w, " [-- ARGS...]")
+	}
+	if len(p.commands) != 0 {
+		fmt.Fprint(w, " <command>")
+	}
+
+	fmt.Fprint(w, "\n\n")
+	if p.metadata.Description != "" {
+		fmt.Fprintf(w, "%s\n\n", p.metadata.Description)
+	}
+	ungrouped, grouped := p.groupedFlags(flags)
+	p.printFlagSection(w, "Flags", ungrouped)
+	for i, groupFlags := range grouped {
+		if len(groupFlags) != 0 {
+			fmt.Fprint(w, "\n")
+			p.printFlagSection(w, p.groups[i], groupFlags)
+		}
+	}
+
+	p.printArgsHelp(w)
+	p.printCommandsHelp(w)
+
+	if p.envHelpSection {
+		p.printEnvHelp(w, flags)
+	}
+}
+
+func (p *Parser) printFlagSection(w io.Writer, heading string, flags []flag) {
+	fmt.Fprintf(w, "%s:\n", heading)
+
+	tw := p.newHelpTabWriter(w)
+	for _, flag := range flags {
+		fmt.Fprintln(tw, flag.getLongDescription())
+	}
+	tw.Flush()
+}
+
+func (p *Parser) newHelpTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, p.helpMinWidth, p.helpTabWidth, p.helpPadding, p.helpPadChar, 0)
+}
+
+func (p *Parser) printEnvHelp(w io.Writer, flags []flag) {
+	var envFlags []flag
+	for _, flag := range flags {
+		if flag.getEnvVarName() != "" {
+			envFlags = append(envFlags, flag)
+		}
+	}
+
+	if len(envFlags) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "\n")
+	fmt.Fprintln(w, "Environment:")
+
+	tw := p.newHelpTabWriter(w)
+	for _, flag := range envFlags {
+		fmt.Fprintf(tw, "  $%s\t--%s", flag.getEnvVarName(), flag.getName())
+		if flag.isRequired() {
+			fmt.Fprintf(tw, " %s", p.requiredLabel)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+func (p *Parser) sortedFlags() []flag {
+	flags := slices.Clone(p.flags)
+	slices.SortStableFunc(flags, func(a, b flag) int {
+		return strings.Compare(a.getName(), b.getName())
+	})
+	return flags
+}
+
+func (p *Parser) visibleFlags() []flag {
+	var flags []flag
+	for _, flag := range p.sortedFlags() {
+		if !flag.isHidden() {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+func (p *Parser) getAppName() string {
+	if p.appName != "" {
+		return p.appName
+	}
+
+	return filepath.Base(os.Args[0])
+}
+
+func (p *Parser) PrintConfig(w io.Writer) {
+	for _, flag := range p.visibleFlags() {
+		if p.isBuiltinFlag(flag) {
+			continue
+		}
+
+		for _, value := range flag.getArgValues() {
+			fmt.Fprintf(w, "--%s=%s\n", flag.getName(), value)
+		}
+	}
+}
+
+func (p *Parser) CanonicalArgs() []string {
+	var args []string
+	for _, flag := range p.sortedFlags() {
+		switch flag.getSource() {
+		case sourceEnv, sourceProfile, sourceArgs:
+		default:
+			// defaults and secrets are not part of the invocation
+			continue
+		}
+
+		if p.isBuiltinFlag(flag) {
+			continue
+		}
+
+		for _, value := range flag.getArgValues() {
+			args = append(args, fmt.Sprintf("--%s=%s", flag.getName(), value))
+		}
+	}
+	return args
+}
+
+func (p *Parser) EnvVars() []string {
+	seen := make(map[string]bool)
+	for _, flag := range p.flags {
+		for _, name := range flag.getEnvVarNames() {
+			seen[name] = true
+		}
+	}
+
+	return sortedKeys(seen)
+}
+
+func (p *Parser) Changed() map[string]string {
+	changed := make(map[string]string)
+	for _, flag := range p.flags {
+		if p.isBuiltinFlag(flag) {
+			continue
+		}
+
+		if _, ok := flag.getDefaultValueString(); ok {
+			if flag.equalsDefault() {
+				continue
+			}
+		} else if flag.getSource() == sourceNone {
+			continue
+		}
+
+		if flag.isSensitive() {
+			changed[flag.getName()] = "****"
+		} else {
+			changed[flag.getName()] = flag.getValueString()
+		}
+	}
+	return changed
+}
+
+func (p *Parser) ExplainConfig(w io.Writer) {
+	tw := p.newHelpTabWriter(w)
+	fmt.Fprintln(tw, "FLAG\tVALUE\tSOURCE")
+	for _, flag := range p.visibleFlags() {
+		if p.isBuiltinFlag(flag) {
+			continue
+		}
+
+		fmt.Fprintf(tw, "--%s\t%s\t%s\n", flag.getName(), flag.getDisplayValue(), flag.getSource())
+	}
+	tw.Flush()
+}
+
+func (p *Parser) isBuiltinFlag(f flag) bool {
+	switch f.getName() {
+	case p.helpFlagName:
+		return true
+	case p.appVersionFlagName:
+		return p.appVersion != ""
+	}
+
+	for _, info := range p.infoFlags {
+		if f.getName() == info.name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Parser) WriteVersion(w io.Writer) {
+	p.printVersion(w)
+}
+
+func (p *Parser) printVersion(w io.Writer) {
+	var details []string
+	if p.metadata.Commit != "" {
+		details = append(details, "commit "+p.metadata.Commit)
+	}
+	if p.metadata.BuildDate != "" {
+		details = append(details, "built "+p.metadata.BuildDate)
+	}
+
+	if len(details) == 0 {
+		fmt.Fprintln(w, p.appVersion)
+		return
+	}
+
+	fmt.Fprintf(w, "%s (%s)\n", p.appVersion, strings.Join(details, ", "))
+}
+
+func (p *Parser) printInfo(w io.Writer, err error) {
+	var infoErr *InfoRequestedError
+	if !errors.As(err, &infoErr) {
+		return
+	}
+
+	fmt.Fprint(w, infoErr.Content)
+	if !strings.HasSuffix(infoErr.Content, "\n") {
+		fmt.Fprintln(w)
+	}
+}
+
+func (p *Parser) printErrs(w io.Writer, errs []error) {
+	if p.jsonErrors {
+		jsonErrs := make([]jsonError, 0, len(errs))
+		for _, err := range errs {
+			jsonErrs = append(jsonErrs, newJSONError(err))
+		}
+		json.NewEncoder(w).Encode(jsonErrs)
+		return
+	}
+
+	for _, err := range errs {
+		fmt.Fprintln(w, err)
+	}
+	fmt.Fprintf(w, "\nUse '--%s' flag for more info.\n", p.helpFlagName)
+}
+
+func (p *Parser) registerFlag(name string, f flag) {
+	if _, ok := p.flagIndex[name]; ok {
+		panic(fmt.Sprintf("flag with name %s is already registered", name))
+	}
+
+	if r := f.getShort(); r != 0 {
+		p.registerShortFlag(r, f)
+	}
+
+	if p.currentGroup != "" {
+		if p.flagGroups == nil {
+			p.flagGroups = make(map[string]string)
+		}
+		p.flagGroups[name] = p.currentGroup
+	}
+
+	p.flags = append(p.flags, f)
+	p.flagIndex[name] = f
+	f.bind(p)
+}
+
+func (p *Parser) registerShortFlag(r rune, f flag) {
+	if r == '-' || !unicode.IsPrint(r) || unicode.IsSpace(r) {
+		panic(fmt.Sprintf("invalid short flag %q for --%s", r, f.getName()))
+	}
+
+	if existing, ok := p.shortIndex[r]; ok {
+		panic(fmt.Sprintf("short flag -%c already used by --%s", r, existing.getName()))
+	}
+
+	p.shortIndex[r] = f
+}
+
+func (p *Parser) set(name, value string) error {
+	if name == p.helpFlagName {
+		if _, err := strconv.ParseBool(value); err != nil {
+			// --help <flag-name>
+			p.helpCalled = true
+			p.helpTopic = value
+			return nil
+		}
+	}
+
+	f, err := p.lookupFlag(name)
+	if err != nil {
+		var unknownFlagErr *UnknownFlagError
+		if !errors.As(err, &unknownFlagErr) {
+			return err
+		}
+		if extra := p.lookupExtraFlags(name); extra != nil {
+			return p.setExtraFlag(extra, name, value)
+		}
+		if p.ignoreUnknown {
+			return nil
+		}
+		return err
+	}
+
+	if err := f.setValueFromString(value); err != nil {
+		return err
+	}
+
+	if p.profiles != nil && f.getName() == p.profileFlagName {
+		return p.applyProfile(value)
+	}
+
+	if loader := p.configLoaders[f.getName()]; loader != nil {
+		// the config file is applied in place, so it overrides the flags
+		// preceding it, while the following ones override it
+		return p.loadConfigFile(f.getName(), value, loader)
+	}
+
+	return nil
+}
+
+// setArg is set for values coming from the command line, additionally
+// setting global flags and rejecting repeated scalar flags in strict mode.
+func (p *Parser) setArg(name, value string) error {
+	if _, err := p.lookupFlag(name); err != nil {
+		if owner, _ := p.lookupGlobalFlag(name); owner != nil {
+			// the latest value wins, as with repeated flags, unless the
+			// owner is in strict mode
+			return owner.setArg(name, value)
+		}
+	}
+
+	if p.strictMode && name != p.helpFlagName {
+		if f, err := p.lookupFlag(name); err == nil && !f.isRepeatable() && !p.isBuiltinFlag(f) {
+			if p.argsSeen[f.getName()] {
+				return fmt.Errorf("--%s is set more than once", f.getName())
+			}
+			p.argsSeen[f.getName()] = true
+		}
+	}
+
+	return p.set(name, value)
+}
+
+func (p *Parser) lookupFlag(name string) (flag, error) {
+	if f := p.flagIndex[name]; f != nil {
+		return f, nil
+	}
+
+	if p.normalizeName {
+		normalized := normalizeFlagName(name)
+		for _, f := range p.flags {
+			if normalizeFlagName(f.getName()) == normalized {
+				return f, nil
+			}
+		}
+	}
+
+	if !p.abbreviations || name == "" {
+		return nil, &UnknownFlagError{Name: name}
+	}
+
+	var candidates []flag
+	for _, f := range p.flags {
+		if f.isAbbreviable() && strings.HasPrefix(f.getName(), name) {
+			candidates = append(candidates, f)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, &UnknownFlagError{Name: name}
+	case 1:
+		return candidates[0], nil
+	}
+
+	names := make([]string, 0, len(candidates))
+	for _, f := range candidates {
+		names = append(names, "--"+f.getName())
+	}
+	slices.Sort(names)
+
+	return nil, fmt.Errorf("ambiguous flag: --%s could be %s", name, strings.Join(names, ", "))
+}
+
+func (p *Parser) parse(args []string) []error {
+	var parseErrs []error
+
+	envStart := time.Now()
+	for _, v := range p.flags {
+		if err := v.setValueFromDefault(p.lookupEnv); err != nil {
+			parseErrs = append(parseErrs, err)
+		}
+		if err := p.setValueFromLayers(v); err != nil {
+			parseErrs = append(parseErrs, err)
+		}
+		if err := v.setValueFromEnv(p.lookupEnv); err != nil {
+			parseErrs = append(parseErrs, err)
+		}
+	}
+	for _, arg := range p.args {
+		if err := arg.setValueFromDefault(p.lookupEnv); err != nil {
+			parseErrs = append(parseErrs, err)
+		}
+	}
+	p.recordTiming("env", envStart)
+
+	defer p.recordTiming("args", time.Now())
+
+	p.passThroughArgs = nil
+	p.argsSeen = make(map[string]bool)
+	p.selectedCommand, p.commandArgs = nil, nil
+	p.argIndex, p.argCount = 0, 0
+
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+
+		if p.passThrough && p.isUnknownFlag(arg) {
+			// stop at the first unknown flag and hand the rest over
+			p.passThroughArgs = append([]string{arg}, args...)
+			break
+		}
+
+		if p.slashFlags && strings.HasPrefix(arg, "/") {
+			// /key or /key:value
+			name, value, found := strings.Cut(arg[1:], ":")
+			if !found {
+				value = "true"
+			}
+			if err := p.setArg(name, value); err != nil {
+				parseErrs = append(parseErrs, err)
+			}
+			continue
+		}
+
+		if r, rest, ok := cutShortFlag(arg); ok {
+			f := p.lookupShortFlag(r)
+			if f == nil {
+				if !p.ignoreUnknown {
+					parseErrs = append(parseErrs, &UnknownFlagError{Name: string(r), Short: true})
+				}
+				continue
+			}
+
+			var err error
+			switch {
+			case strings.HasPrefix(rest, "="):
+				// -k=value
+				if f.isBoolFlag() && rest == "=" {
+					err = &MissingValueError{Name: f.getName(), Expected: "true or false"}
+				} else if rest == "=" && !f.acceptsEmpty() {
+					err = &MissingValueError{Name: f.getName(), Expected: "a value after '='"}
+				} else {
+					err = p.setArg(f.getName(), rest[1:])
+				}
+			case rest != "" && !f.isBoolFlag():
+				// -kvalue
+				err = p.setArg(f.getName(), rest)
+			case rest != "":
+				// -abc
+				var n int
+				n, err = p.setShortCluster(arg, args)
+				args = args[n:]
+			case !f.isBoolFlag() && (len(args) == 0 || p.isLongFlag(args[0])):
+				err = &MissingValueError{Name: f.getName(), Expected: "a value"}
+			case f.isBoolFlag() && !p.hasValueArg(f, args):
+				// -k
+				err = p.setArg(f.getName(), "true")
+			default:
+				// -k value
+				err = p.setArg(f.getName(), args[0])
+				args = args[1:]
+			}
+
+			if err != nil {
+				parseErrs = append(parseErrs, err)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(arg, "--") {
+			if cmd := p.lookupCommand(arg); cmd != nil {
+				// the rest is up to the subcommand
+				p.selectedCommand, p.commandArgs = cmd, args
+				break
+			}
+			if ok, err := p.setNextArg(arg); ok {
+				if err != nil {
+					parseErrs = append(parseErrs, err)
+				}
+				continue
+			}
+			if p.unknownArgHandler != nil {
+				p.argCount++
+				if err := p.unknownArgHandler(arg); err != nil {
+					return append(parseErrs, err)
+				}
+				continue
+			}
+			if len(p.commands) != 0 {
+				return append(parseErrs, p.unknownCommand(arg))
+			}
+			parseErrs = append(parseErrs, &UnexpectedArgumentsError{Args: []string{arg}})
+			return parseErrs
+		}
+
+		arg = strings.TrimPrefix(arg, "--")
+
+		if arg == "" {
+			// end of flags
+			for len(args) > 0 {
+				ok, err := p.setNextArg(args[0])
+				if !ok {
+					break
+				}
+				if err != nil {
+					parseErrs = append(parseErrs, err)
+				}
+				args = args[1:]
+			}
+			if p.restArgs != nil {
+				*p.restArgs = append([]string(nil), args...)
+				p.argCount += len(args)
+				break
+			}
+			if p.unknownArgHandler != nil {
+				for _, arg := range args {
+					p.argCount++
+					if err := p.unknownArgHandler(arg); err != nil {
+						return append(parseErrs, err)
+					}
+				}
+				break
+			}
+			if len(args) != 0 {
+				parseErrs = append(parseErrs, &UnexpectedArgumentsError{Args: args})
+				return parseErrs
+			}
+			break
+		}
+
+		if name, value, found := strings.Cut(arg, "="); found {
+			// --key=value
+			if f, err := p.lookupArgFlag(name); err == nil && value == "" {
+				if f.isBoolFlag() {
+					parseErrs = append(parseErrs, &MissingValueError{Name: name, Expected: "true or false"})
+					continue
+				}
+				if !f.acceptsEmpty() {
+					// clearer than the raw parse error, e.g. for --port=
+					parseErrs = append(parseErrs, &MissingValueError{Name: f.getName(), Expected: "a value after '='"})
+					continue
+				}
+			}
+			if err := p.setArg(name, value); err != nil {
+				parseErrs = append(parseErrs, err)
+			}
+			continue
+		}
+
+		if p.lenientAssignment && len(args) != 0 && strings.HasPrefix(args[0], "=") {
+			// --key = value or --key =value
+			value := strings.TrimPrefix(args[0], "=")
+			args = args[1:]
+			if value == "" {
+				if len(args) == 0 {
+					parseErrs = append(parseErrs, &MissingValueError{Name: arg, Expected: "a value after '='"})
+					continue
+				}
+				value = args[0]
+				args = args[1:]
+			}
+			if err := p.setArg(arg, value); err != nil {
+				parseErrs = append(parseErrs, err)
+			}
+			continue
+		}
+
+		if f, err := p.lookupArgFlag(arg); err == nil && !f.isBoolFlag() {
+			if len(args) == 0 || p.isLongFlag(args[0]) {
+				parseErrs = append(parseErrs, &MissingValueError{Name: f.getName(), Expected: "a value"})
+				continue
+			}
+		} else if !p.hasValueArg(f, args) {
+			// --key (boolean flag)
+			if err := p.setArg(arg, "true"); err != nil {
+				parseErrs = append(parseErrs, err)
+			}
+			continue
+		}
+
+		// --key value
+		if err := p.setArg(arg, args[0]); err != nil {
+			parseErrs = append(parseErrs, err)
+		}
+		args = args[1:]
+	}
+
+	return parseErrs
+}
+
+// isFlag reports whether arg in the value position is a flag rather
+// than a value.
+func (p *Parser) isFlag(arg string) bool {
+	if strings.HasPrefix(arg, "--") {
+		return true
+	}
+
+	if p.lookupCommand(arg) != nil {
+		// a subcommand name is never a value of a bool flag
+		return true
+	}
+
+	if r, _, ok := cutShortFlag(arg); ok {
+		return p.lookupShortFlag(r) != nil
+	}
+
+	return p.isSlashFlag(arg)
+}
+
+// setShortCluster sets POSIX-style clustered short flags like -vqf. All
+// but the last flag in the cluster must be bool ones, while the last one may
+// take the next argument as its value. It returns the number of args consumed.
+func (p *Parser) setShortCluster(arg string, args []string) (int, error) {
+	cluster := arg[1:]
+	for cluster != "" {
+		r, size := utf8.DecodeRuneInString(cluster)
+		cluster = cluster[size:]
+
+		f := p.lookupShortFlag(r)
+		switch {
+		case r == '=':
+			return 0, &UnexpectedArgumentsError{Args: []string{arg}}
+		case f == nil && p.ignoreUnknown:
+			continue
+		case f == nil:
+			return 0, &UnknownFlagError{Name: string(r), Short: true}
+		case f.isBoolFlag():
+			if err := p.setArg(f.getName(), "true"); err != nil {
+				return 0, err
+			}
+			continue
+		case cluster != "":
+			return 0, fmt.Errorf("flag -%c requires a value, so it must be the last one in %s", r, arg)
+		case len(args) == 0 || p.isLongFlag(args[0]):
+			return 0, &MissingValueError{Name: f.getName(), Expected: "a value"}
+		}
+
+		return 1, p.setArg(f.getName(), args[0])
+	}
+
+	return 0, nil
+}
+
+// sanitizeArgs masks values of sensitive flags (secrets and redacted ones)
+// in the given args. It follows the classification done by parse, so it has
+// to be kept in sync with it.
+func (p *Parser) sanitizeArgs(args []string) []string {
+	const mask = "****"
+
+	sanitized := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if p.passThrough && p.isUnknownFlag(arg) {
+			return append(sanitized, args[i:]...)
+		}
+
+		if p.slashFlags && strings.HasPrefix(arg, "/") {
+			// /key or /key:value
+			name, _, found := strings.Cut(arg[1:], ":")
+			if f, _ := p.lookupArgFlag(name); found && f != nil && f.isSensitive() {
+				arg = "/" + name + ":" + mask
+			}
+			sanitized = append(sanitized, arg)
+			continue
+		}
+
+		var (
+			f      flag
+			prefix string
+			inline bool
+		)
+
+		if r, rest, ok := cutShortFlag(arg); ok {
+			f = p.lookupShortFlag(r)
+			if f != nil && f.isBoolFlag() && rest != "" && !strings.HasPrefix(rest, "=") {
+				// -abc, only the last flag of the cluster could take a value
+				last, _ := utf8.DecodeLastRuneInString(rest)
+				f, rest = p.lookupShortFlag(last), ""
+			}
+			prefix, inline = arg[:len(arg)-len(rest)], rest != ""
+			if strings.HasPrefix(rest, "=") {
+				prefix += "="
+			}
+		} else if arg == "--" {
+			return append(sanitized, args[i:]...)
+		} else if name, ok := strings.CutPrefix(arg, "--"); ok {
+			name, _, inline = strings.Cut(name, "=")
+			f, _ = p.lookupArgFlag(name)
+			prefix = "--" + name + "="
+
+			if !inline && p.lenientAssignment && i+1 < len(args) && strings.HasPrefix(args[i+1], "=") {
+				// --key = value or --key =value
+				sanitized = append(sanitized, arg)
+				switch sensitive := f != nil && f.isSensitive(); {
+				case args[i+1] == "=" && i+2 < len(args):
+					sanitized = append(sanitized, "=", args[i+2])
+					if sensitive {
+						sanitized[len(sanitized)-1] = mask
+					}
+					i += 2
+				case sensitive && args[i+1] != "=":
+					sanitized = append(sanitized, "="+mask)
+					i++
+				default:
+					sanitized = append(sanitized, args[i+1])
+					i++
+				}
+				continue
+			}
+		} else if cmd := p.lookupCommand(arg); cmd != nil {
+			// the rest is up to the subcommand
+			sanitized = append(sanitized, arg)
+			return append(sanitized, cmd.parser.sanitizeArgs(args[i+1:])...)
+		}
+
+		switch {
+		case f == nil || !f.isSensitive():
+			sanitized = append(sanitized, arg)
+		case inline:
+			sanitized = append(sanitized, prefix+mask)
+		case p.hasValueArg(f, args[i+1:]):
+			sanitized = append(sanitized, arg, mask)
+			i++
+		default:
+			sanitized = append(sanitized, arg)
+		}
+	}
+
+	return sanitized
+}
+
+// isLongFlag reports whether arg terminates the space-separated value of a
+// non-bool flag. Unlike with isFlag, single-dash tokens are taken as values
+// there (e.g. --message -x or --offset -5), as GNU getopt does.
+func (p *Parser) isLongFlag(arg string) bool {
+	return strings.HasPrefix(arg, "--") || p.isSlashFlag(arg)
+}
+
+// hasValueArg reports whether the first of args is the space-separated value
+// of the flag f (which is nil for unknown flags).
+func (p *Parser) hasValueArg(f flag, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	if f != nil && !f.isBoolFlag() {
+		return !p.isLongFlag(args[0])
+	}
+
+	if p.isFlag(args[0]) {
+		return false
+	}
+
+	if _, err := p.parseBool(args[0]); err != nil && len(p.args) != 0 {
+		// with positional arguments declared, only bool words are taken as
+		// values of bool flags, except for the --help topic
+		return f != nil && f.getName() == p.helpFlagName
+	}
+
+	return true
+}
+
+func (p *Parser) isUnknownFlag(arg string) bool {
+	var name string
+
+	switch r, _, ok := cutShortFlag(arg); {
+	case ok:
+		return p.lookupShortFlag(r) == nil
+	case strings.HasPrefix(arg, "--") && arg != "--":
+		name, _, _ = strings.Cut(arg[2:], "=")
+	case p.slashFlags && strings.HasPrefix(arg, "/"):
+		name, _, _ = strings.Cut(arg[1:], ":")
+	default:
+		return false
+	}
+
+	_, err := p.lookupArgFlag(name)
+	var unknownFlagErr *UnknownFlagError
+	return errors.As(err, &unknownFlagErr) && p.lookupExtraFlags(name) == nil
+}
+
+// normalizeFlagName converts camelCase and snake_case names to kebab-case,
+// e.g. logLevel, log_level and LOG_LEVEL all become log-level.
+func normalizeFlagName(name string) string {
+	runes := []rune(name)
+	b := &strings.Builder{}
+
+	for i, r := range runes {
+		if r == '_' {
+			b.WriteByte('-')
+			continue
+		}
+
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('-')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// cutShortFlag splits a -k[rest] argument into the short flag name and
+// the rest of the argument.
+func cutShortFlag(arg string) (rune, string, bool) {
+	if len(arg) < 2 || arg[0] != '-' || arg[1] == '-' {
+		return 0, "", false
+	}
+
+	r, size := utf8.DecodeRuneInString(arg[1:])
+	return r, arg[1+size:], true
+}
+
+// isSlashFlag reports whether arg in the value position is a slash-style
+// flag. Unlike in the flag position, only the registered flag names are
+// recognized here, so that values like absolute paths are not mistaken
+// for flags.
+func (p *Parser) isSlashFlag(arg string) bool {
+	if !p.slashFlags || !strings.HasPrefix(arg, "/") {
+		return false
+	}
+
+	name, _, _ := strings.Cut(arg[1:], ":")
+	_, ok := p.flagIndex[name]
+	return ok
+}
+
+// applySecrets resolves the secrets of flags not set from envvars, profiles
+// or the command line, so the resolver (e.g. a keychain prompt) is only
+// called when the value is actually needed.
+func (p *Parser) applySecrets() []error {
+	if p.helpCalled || p.versionCalled {
+		return nil
+	}
+	for _, info := range p.infoFlags {
+		if info.called {
+			return nil
+		}
+	}
+
+	defer p.recordTiming("secrets", time.Now())
+
+	var errs []error
+	for _, flag := range p.flags {
+		if err := flag.setValueFromSecret(p.secretResolver); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+func (p *Parser) applyDefaultFuncs() []error {
+	var errs []error
+	for _, flag := range append(slices.Clone(p.flags), p.args...) {
+		if err := flag.setValueFromDefaultFunc(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+func (p *Parser) checkRequiredFlags() []error {
+	var checkErrs []error
+
+	for _, flag := range p.flags {
+		if (flag.isRequired() || flag.isRequiredByEnv(p.lookupEnv)) && !flag.isSet() {
+			checkErrs = append(checkErrs, &MissingFlagError{Name: flag.getName()})
+		} else if p.strictMode && flag.isRequired() && flag.getValueString() == "" {
+			checkErrs = append(checkErrs, &InvalidValueError{Name: flag.getName(), Err: errors.New("must not be empty")})
+		}
+	}
+
+	return checkErrs
+}
+
+func (p *Parser) Validate() error {
+	var errs []error
+
+	for _, flag := range p.flags {
+		if flag.isRequired() && flag.isHidden() && flag.getEnvVarName() == "" && flag.getSecretKey() == "" {
+			errs = append(errs, fmt.Errorf("flag --%s is required and hidden, but has no env var or secret to be set from", flag.getName()))
+		}
+	}
+
+	errs = append(errs, p.validateArgs()...)
+
+	return errors.Join(errs...)
+}
+
+func (p *Parser) AuditHelp() []string {
+	var warnings []string
+
+	for _, flag := range p.flags {
+		if p.isBuiltinFlag(flag) || flag.isObsolete() {
+			continue
+		}
+
+		name := flag.getName()
+		if strings.TrimSpace(flag.getHelpMessage()) == "" {
+			warnings = append(warnings, fmt.Sprintf("--%s has no help message", name))
+		}
+		if flag.isRequired() && flag.isHidden() {
+			warnings = append(warnings, fmt.Sprintf("--%s is required, but hidden from help", name))
+		}
+		if !flag.isBoolFlag() && flag.getPlaceholder() == "" {
+			warnings = append(warnings, fmt.Sprintf("--%s has no value placeholder", name))
+		}
+	}
+
+	return warnings
+}
+
+func (p *Parser) checkRedundantDefaults() []string {
+	if !p.warnRedundantDefaults {
+		return nil
+	}
+
+	var warnings []string
+	for _, f := range p.flags {
+		switch f.getSource() {
+		case sourceArgs, sourceEnv:
+		default:
+			continue
+		}
+
+		if f.equalsDefault() {
+			def, _ := f.getDefaultValueString()
+			warnings = append(warnings, fmt.Sprintf("--%s is explicitly set to its default value %s", f.getName(), def))
+		}
+	}
+
+	return warnings
+}
+
+func (p *Parser) Timings() map[string]time.Duration {
+	return p.timings
+}
+
+func (p *Parser) recordTiming(phase string, start time.Time) {
+	if p.timings != nil {
+		p.timings[phase] = time.Since(start)
+	}
+}
+
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
+func (p *Parser) checkSameSource() []error {
+	var checkErrs []error
+
+	for _, names := range p.sameSourceGroups {
+		var fromEnv, fromArgs []string
+		for _, name := range names {
+			switch p.flagIndex[name].getSource() {
+			case sourceEnv:
+				fromEnv = append(fromEnv, "--"+name)
+			case sourceArgs:
+				fromArgs = append(fromArgs, "--"+name)
+			}
+		}
+
+		if len(fromEnv) != 0 && len(fromArgs) != 0 {
+			checkErrs = append(checkErrs, fmt.Errorf(
+				"flags %s and %s must be set from the same source, not mixing command line and environment",
+				strings.Join(fromArgs, ", "), strings.Join(fromEnv, ", "),
+			))
 		}
 	}
 
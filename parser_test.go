@@ -16,8 +16,18 @@ package flenv
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,6 +62,316 @@ func TestParserPrintHelp(t *testing.T) {
 	assert.Equal(t, helpMessage, buf.String())
 }
 
+func TestParserPrintHelpHidden(t *testing.T) {
+	var b, h bool
+
+	p := New(
+		WithAppName("test-app"),
+		WithEnvHelpSection(),
+	)
+	p.Bool(&b, "test-bool-flag", "Test bool flag")
+	p.Bool(&h, "test-hidden-flag", "Test hidden flag").Hidden()
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	assert.Contains(t, buf.String(), "--test-bool-flag")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.NotContains(t, buf.String(), "HIDDEN")
+}
+
+func TestParserObsolete(t *testing.T) {
+	newParser := func() *Parser {
+		var (
+			b bool
+			s string
+		)
+		p := New(WithAppName("test-app"))
+		p.Bool(&b, "old-feature", "Old feature").Obsolete("use --new-feature instead")
+		p.String(&s, "new-feature", "New feature")
+		return p
+	}
+
+	t.Run("Args", func(t *testing.T) {
+		errs := newParser().parse([]string{"--old-feature"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--old-feature has been removed: use --new-feature instead")
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("OLD_FEATURE", "true")
+
+		errs := newParser().parse(nil)
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--old-feature has been removed: use --new-feature instead")
+	})
+
+	t.Run("Unused", func(t *testing.T) {
+		errs := newParser().parse([]string{"--new-feature=foo"})
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		p := newParser()
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+		assert.Contains(t, buf.String(), "Usage: test-app [--help] [--new-feature=STRING]\n")
+		assert.Contains(t, buf.String(), "  --old-feature         Old feature (removed) [$OLD_FEATURE]\n")
+	})
+}
+
+func TestParserPrintHelpCustomLabels(t *testing.T) {
+	var (
+		i int
+		s string
+	)
+
+	p := New(
+		WithAppName("test-app"),
+		WithRequiredLabel("[REQUIRED]"),
+		WithDefaultLabelFormat("= %s"),
+		WithEnvHelpSection(),
+	)
+	p.Int(&i, "port", "Test int flag").Required()
+	p.String(&s, "name", "Test string flag").Default("foo")
+	p.String(&s, "tls-cert", "Test string flag").RequiredWhenEnv("MODE", "prod")
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	assert.Contains(t, buf.String(), "  --name=STRING      Test string flag = foo [$NAME]\n")
+	assert.Contains(t, buf.String(), "  --port=INT         Test int flag [REQUIRED] [$PORT]\n")
+	assert.Contains(t, buf.String(), "  --tls-cert=STRING  Test string flag [REQUIRED when $MODE=prod] [$TLS_CERT]\n")
+	assert.Contains(t, buf.String(), "Environment:\n"+
+		"  $NAME      --name\n"+
+		"  $PORT      --port [REQUIRED]\n"+
+		"  $TLS_CERT  --tls-cert\n")
+
+	buf.Reset()
+	p.WriteEnvTemplate(buf)
+
+	assert.Equal(t, "# --name: Test string flag = foo\n"+
+		"#NAME=foo\n"+
+		"\n"+
+		"# --port: Test int flag [REQUIRED]\n"+
+		"PORT=\n"+
+		"\n"+
+		"# --tls-cert: Test string flag\n"+
+		"#TLS_CERT=\n", buf.String())
+}
+
+func TestParserPrintHelpTypeHints(t *testing.T) {
+	var (
+		d time.Duration
+		i int
+		s string
+	)
+
+	p := New(
+		WithAppName("test-app"),
+		WithTypeHints(),
+	)
+	p.Duration(&d, "timeout", "Test duration flag")
+	p.Int(&i, "port", "Test int flag").Required()
+	p.String(&s, "name", "Test string flag").Placeholder("NAME")
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	assert.Contains(t, buf.String(), "Usage: test-app --port=<int> [--help] [--name=NAME] [--timeout=<duration>]\n")
+}
+
+func TestParserPrintHelpDefaultsInUsage(t *testing.T) {
+	var (
+		d time.Duration
+		i int
+		s string
+	)
+
+	p := New(
+		WithAppName("test-app"),
+		WithDefaultsInUsage(),
+	)
+	p.Duration(&d, "timeout", "Test duration flag").Default(30 * time.Second)
+	p.Int(&i, "port", "Test int flag").Required()
+	p.String(&s, "name", "Test string flag")
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	assert.Contains(t, buf.String(), "Usage: test-app --port=INT [--help] [--name=STRING] [--timeout=DURATION (30s)]\n")
+}
+
+func TestParserPrintFlagHelp(t *testing.T) {
+	newParser := func() *Parser {
+		var i int
+
+		p := New(WithAppName("test-app"))
+		p.Int(&i, "test-int-flag", "Test int flag").Default(10)
+		return p
+	}
+
+	t.Run("ExistingFlag", func(t *testing.T) {
+		p := newParser()
+		errs := p.parse([]string{"--help", "test-int-flag"})
+		require.Empty(t, errs)
+		require.True(t, p.helpCalled)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+
+		const helpMessage = "--test-int-flag=INT\n" +
+			"  Test int flag\n" +
+			"  Default: 10\n" +
+			"  Environment: $TEST_INT_FLAG\n"
+
+		assert.Equal(t, helpMessage, buf.String())
+	})
+
+	t.Run("Example", func(t *testing.T) {
+		var d time.Duration
+
+		p := New(WithAppName("test-app"))
+		p.Duration(&d, "timeout", "Test duration flag").Example("1m30s")
+
+		errs := p.parse([]string{"--help", "timeout"})
+		require.Empty(t, errs)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+
+		const helpMessage = "--timeout=DURATION\n" +
+			"  Test duration flag\n" +
+			"  Environment: $TIMEOUT\n" +
+			"  Example: --timeout=1m30s\n"
+
+		assert.Equal(t, helpMessage, buf.String())
+	})
+
+	t.Run("NonexistentFlag", func(t *testing.T) {
+		p := newParser()
+		errs := p.parse([]string{"--help=nonexistent-flag"})
+		require.Empty(t, errs)
+		require.True(t, p.helpCalled)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+
+		assert.True(t, strings.HasPrefix(buf.String(), "Unknown flag: --nonexistent-flag, showing all flags.\n\nUsage: test-app "))
+		assert.Contains(t, buf.String(), "--test-int-flag=INT")
+	})
+}
+
+func TestParserPrintHelpShortFlags(t *testing.T) {
+	var (
+		b bool
+		i int
+	)
+
+	p := New(WithAppName("test-app"))
+	p.Bool(&b, "test-bool-flag", "Test bool flag").Short('b')
+	p.Int(&i, "test-int-flag", "Test int flag")
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	assert.Contains(t, buf.String(), "Flags:\n"+
+		"      --help               Show help message\n"+
+		"  -b, --test-bool-flag     Test bool flag [$TEST_BOOL_FLAG]\n"+
+		"      --test-int-flag=INT  Test int flag [$TEST_INT_FLAG]\n")
+}
+
+func TestParserPrintHelpPadding(t *testing.T) {
+	var b bool
+
+	p := New(
+		WithAppName("test-app"),
+		WithHelpPadding(0, 0, 4, '.'),
+	)
+	p.Bool(&b, "test-bool-flag", "Test bool flag")
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	const helpMessage = "Usage: test-app [--help] [--test-bool-flag]\n\n" +
+		"Flags:\n" +
+		"  --help..............Show help message\n" +
+		"  --test-bool-flag....Test bool flag [$TEST_BOOL_FLAG]\n"
+
+	assert.Equal(t, helpMessage, buf.String())
+}
+
+func TestParserPrintEnvHelp(t *testing.T) {
+	var (
+		b bool
+		i int
+		s string
+	)
+
+	p := New(
+		WithAppName("test-app"),
+		WithEnvHelpSection(),
+	)
+	p.Bool(&b, "test-bool-flag", "Test bool flag")
+	p.Int(&i, "test-int-flag", "Test int flag").Required()
+	p.String(&s, "test-string-flag", "Test string flag").Env("CUSTOM_STRING_VAR")
+
+	buf := bytes.NewBuffer(nil)
+	p.printHelp(buf)
+
+	const envSection = "Environment:\n" +
+		"  $TEST_BOOL_FLAG     --test-bool-flag\n" +
+		"  $TEST_INT_FLAG      --test-int-flag (required)\n" +
+		"  $CUSTOM_STRING_VAR  --test-string-flag\n"
+
+	assert.Contains(t, buf.String(), "\n\n"+envSection)
+	assert.NotContains(t, buf.String(), "HELP")
+}
+
+func TestParserGetAppName(t *testing.T) {
+	t.Run("Explicit", func(t *testing.T) {
+		p := New(WithAppName("test-app"))
+		assert.Equal(t, "test-app", p.getAppName())
+	})
+
+	t.Run("ArgsBasename", func(t *testing.T) {
+		args := os.Args
+		t.Cleanup(func() { os.Args = args })
+		os.Args = []string{"/tmp/go-build123/exe/myapp"}
+
+		p := New()
+		assert.Equal(t, "myapp", p.getAppName())
+	})
+}
+
+func TestParserSkipArgs(t *testing.T) {
+	args := os.Args
+	t.Cleanup(func() { os.Args = args })
+
+	t.Run("Skipped", func(t *testing.T) {
+		os.Args = []string{"myapp", "serve", "--test-flag=10"}
+
+		var i int
+		p := New(WithSkipArgs(1))
+		p.Int(&i, "test-flag", "Test flag")
+		p.Parse()
+
+		assert.Equal(t, 10, i)
+	})
+
+	t.Run("MoreThanProvided", func(t *testing.T) {
+		os.Args = []string{"myapp", "serve"}
+
+		var i int
+		p := New(WithSkipArgs(2))
+		p.Int(&i, "test-flag", "Test flag")
+		p.Parse()
+
+		assert.Equal(t, 0, i)
+	})
+}
+
 func TestParserPrintError(t *testing.T) {
 	p := New()
 
@@ -61,6 +381,204 @@ func TestParserPrintError(t *testing.T) {
 	assert.Equal(t, "test-error\n\nUse '--help' flag for more info.\n", buf.String())
 }
 
+func TestParserPrintJSONErrors(t *testing.T) {
+	var i int
+
+	p := New(WithJSONErrors())
+	p.Int(&i, "test-flag", "Test flag").Required()
+
+	errs := p.parse([]string{"--nonexistent-flag=abc"})
+	errs = append(errs, p.checkRequiredFlags()...)
+	require.Len(t, errs, 2)
+
+	buf := bytes.NewBuffer(nil)
+	p.printErrs(buf, errs)
+
+	const expected = `[` +
+		`{"type":"unknown_flag","flag":"nonexistent-flag","message":"unknown flag: --nonexistent-flag"},` +
+		`{"type":"missing_required","flag":"test-flag","message":"missing required flag: --test-flag"}` +
+		`]`
+
+	assert.JSONEq(t, expected, buf.String())
+}
+
+func TestParserPrintConfig(t *testing.T) {
+	var (
+		i     int
+		token string
+	)
+
+	p := New(WithAppVersion("1.2.3"))
+	p.Int(&i, "port", "Test flag").Default(8080)
+	p.String(&token, "token", "Test flag").Redact(func(s string) string {
+		if len(s) <= 4 {
+			return "****"
+		}
+		return "****" + s[len(s)-4:]
+	})
+
+	err := p.ParseArgs([]string{"--token=0123456789abcdef"})
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	p.PrintConfig(buf)
+
+	assert.Equal(t, "--port=8080\n--token=****cdef\n", buf.String())
+	assert.Equal(t, "0123456789abcdef", token)
+}
+
+func TestParserCanonicalArgs(t *testing.T) {
+	t.Setenv("HOST", "example.com")
+
+	var (
+		host  string
+		port  int
+		items []string
+		token string
+		debug bool
+	)
+
+	p := New()
+	p.String(&host, "host", "Test flag")
+	p.Int(&port, "port", "Test flag").Default(8080)
+	p.StringSlice(&items, "item", "Test flag")
+	p.String(&token, "token", "Test flag").Redact(func(string) string {
+		return "****"
+	})
+	p.Bool(&debug, "debug", "Test flag")
+
+	err := p.ParseArgs([]string{"--item", "b", "--token=secret", "--item=a,c"})
+	require.NoError(t, err)
+
+	expected := []string{
+		"--host=example.com",
+		"--item=b,a,c",
+		"--token=****",
+	}
+	assert.Equal(t, expected, p.CanonicalArgs())
+}
+
+func TestParserEnvVars(t *testing.T) {
+	var (
+		s     string
+		b     bool
+		items []string
+		key   string
+		i     int
+	)
+	p := New(WithEnvVarPrefix("APP_"))
+	p.String(&s, "test-string-flag", "Test flag")
+	p.BoolWithEnvDefault(&b, "feature", "Test flag", "FEATURE_DEFAULT")
+	p.StringSlice(&items, "item", "Test flag").EnvIndexed()
+	p.String(&key, "license-key", "Test flag").Env("APP_TEST_STRING_FLAG").RequiredWhenEnv("APP_ENV", "production")
+	p.Int(&i, "test-int-flag", "Test flag").Env("")
+
+	assert.Equal(t, []string{
+		"APP_ENV",
+		"APP_FEATURE",
+		"APP_ITEM_*",
+		"APP_TEST_STRING_FLAG",
+		"FEATURE_DEFAULT",
+	}, p.EnvVars())
+}
+
+func TestParserChanged(t *testing.T) {
+	t.Setenv("HOST", "example.com")
+
+	var (
+		host    string
+		port    int
+		timeout time.Duration
+		workers int
+		token   string
+		debug   bool
+	)
+
+	p := New()
+	p.String(&host, "host", "Test flag")
+	p.Int(&port, "port", "Test flag").Default(8080)
+	p.Duration(&timeout, "timeout", "Test flag").Default(10 * time.Second)
+	p.Int(&workers, "workers", "Test flag").Default(1)
+	p.String(&token, "token", "Test flag").Redact(func(s string) string {
+		return s
+	})
+	p.Bool(&debug, "debug", "Test flag")
+
+	err := p.ParseArgs([]string{"--port=8080", "--timeout=20s", "--token=secret"})
+	require.NoError(t, err)
+
+	expected := map[string]string{
+		"host":    "example.com",
+		"timeout": "20s",
+		"token":   "****",
+	}
+	assert.Equal(t, expected, p.Changed())
+}
+
+func TestParserExplainConfig(t *testing.T) {
+	t.Setenv("HOST", "example.com")
+
+	var (
+		host    string
+		port    int
+		timeout time.Duration
+		debug   bool
+	)
+
+	p := New()
+	p.String(&host, "host", "Test flag")
+	p.Int(&port, "port", "Test flag").Default(8080)
+	p.Duration(&timeout, "timeout", "Test flag")
+	p.Bool(&debug, "debug", "Test flag")
+
+	err := p.ParseArgs([]string{"--timeout=5s"})
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	p.ExplainConfig(buf)
+
+	const expected = "FLAG       VALUE        SOURCE\n" +
+		"--debug    false        none\n" +
+		"--host     example.com  env\n" +
+		"--port     8080         default\n" +
+		"--timeout  5s           args\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestParserMetadata(t *testing.T) {
+	meta := Metadata{
+		Name:        "meta-app",
+		Version:     "1.2.3",
+		Commit:      "abc1234",
+		BuildDate:   "2025-01-02",
+		Description: "Does things.",
+	}
+
+	t.Run("Applied", func(t *testing.T) {
+		p := New(WithMetadata(meta))
+
+		buf := bytes.NewBuffer(nil)
+		p.printVersion(buf)
+		assert.Equal(t, "1.2.3 (commit abc1234, built 2025-01-02)\n", buf.String())
+
+		buf.Reset()
+		p.printHelp(buf)
+		assert.True(t, strings.HasPrefix(buf.String(), "Usage: meta-app [--help] [--version]\n\nDoes things.\n\nFlags:\n"))
+	})
+
+	t.Run("OptionsOverride", func(t *testing.T) {
+		for _, opts := range [][]Option{
+			{WithAppName("test-app"), WithMetadata(meta)},
+			{WithMetadata(meta), WithAppName("test-app")},
+		} {
+			p := New(opts...)
+			assert.Equal(t, "test-app", p.getAppName())
+			assert.Equal(t, "1.2.3", p.appVersion)
+		}
+	})
+}
+
 func TestParserPrintVersion(t *testing.T) {
 	p := New(
 		WithAppVersion("1.2.3"),
@@ -72,6 +590,51 @@ func TestParserPrintVersion(t *testing.T) {
 	assert.Equal(t, "1.2.3\n", buf.String())
 }
 
+func TestParserWriteHelp(t *testing.T) {
+	t.Run("Help", func(t *testing.T) {
+		var b bool
+		p := New(WithAppName("test-app"))
+		p.Bool(&b, "recursive", "Copy directories recursively")
+
+		err := p.ParseArgs([]string{"--help"})
+		require.ErrorIs(t, err, ErrHelp)
+
+		buf := bytes.NewBuffer(nil)
+		p.WriteHelp(buf)
+
+		assert.Equal(t, "Usage: test-app [--help] [--recursive]\n\n"+
+			"Flags:\n"+
+			"  --help       Show help message\n"+
+			"  --recursive  Copy directories recursively [$RECURSIVE]\n", buf.String())
+	})
+
+	t.Run("HelpTopic", func(t *testing.T) {
+		var b bool
+		p := New(WithAppName("test-app"))
+		p.Bool(&b, "recursive", "Copy directories recursively")
+
+		err := p.ParseArgs([]string{"--help", "recursive"})
+		require.ErrorIs(t, err, ErrHelp)
+
+		buf := bytes.NewBuffer(nil)
+		p.WriteHelp(buf)
+
+		assert.Equal(t, "--recursive\n  Copy directories recursively\n  Environment: $RECURSIVE\n", buf.String())
+	})
+}
+
+func TestParserWriteVersion(t *testing.T) {
+	p := New(WithAppVersion("1.2.3"))
+
+	err := p.ParseArgs([]string{"--version"})
+	require.ErrorIs(t, err, ErrVersion)
+
+	buf := bytes.NewBuffer(nil)
+	p.WriteVersion(buf)
+
+	assert.Equal(t, "1.2.3\n", buf.String())
+}
+
 func TestParserRegisterExistingFlag(t *testing.T) {
 	var v string
 
@@ -157,40 +720,2500 @@ func TestParserParse(t *testing.T) {
 	})
 }
 
-func TestParserCheckRequiredFlags(t *testing.T) {
-	t.Run("NoRequiredFlags", func(t *testing.T) {
-		var i int
-		p := New()
-		p.Int(&i, "test-flag", "Test flag")
+func TestParserParseMaxArgs(t *testing.T) {
+	newParser := func(s *string) *Parser {
+		p := New(WithMaxArgs(2))
+		p.String(s, "test-flag", "Test flag")
+		return p
+	}
 
-		parseErrs := p.parse(nil)
-		require.Empty(t, parseErrs)
+	t.Run("WithinLimit", func(t *testing.T) {
+		var s string
+		err := newParser(&s).ParseArgs([]string{"--test-flag", "foo"})
+		require.NoError(t, err)
+		assert.Equal(t, "foo", s)
+	})
 
-		checkErrs := p.checkRequiredFlags()
-		assert.Empty(t, checkErrs)
+	t.Run("PastLimit", func(t *testing.T) {
+		var s string
+		err := newParser(&s).ParseArgs([]string{"--test-flag", "foo", "--test-flag=bar"})
+		assert.EqualError(t, err, "too many arguments: got 3, at most 2 allowed")
+		assert.Equal(t, "", s)
 	})
+}
 
-	t.Run("RequiredFlagNotSet", func(t *testing.T) {
-		var i int
-		p := New()
-		p.Int(&i, "test-flag", "Test flag").Required()
+func TestParserParseEchoArgsOnError(t *testing.T) {
+	newParser := func(opts ...Option) *Parser {
+		var (
+			port  int
+			token string
+			key   string
+		)
+		p := New(opts...)
+		p.Int(&port, "port", "Test flag")
+		p.String(&token, "token", "Test flag").Short('t').Redact(func(string) string {
+			return "****"
+		})
+		p.String(&key, "key", "Test flag").FromSecret("key")
+		return p
+	}
 
-		parseErrs := p.parse(nil)
-		require.Empty(t, parseErrs)
+	t.Run("Masked", func(t *testing.T) {
+		p := newParser(WithEchoArgsOnError(), WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		err := p.ParseArgs([]string{"--port=abc", "--token", "secret", "-tsecret", "--key=secret"})
+		assert.EqualError(t, err, "invalid value for --port: strconv.Atoi: parsing \"abc\": invalid syntax\n"+
+			"provided args: --port=abc --token **** -t**** --key=****")
+	})
 
-		checkErrs := p.checkRequiredFlags()
+	t.Run("SlashFlags", func(t *testing.T) {
+		p := newParser(WithEchoArgsOnError(), WithSlashFlags(), WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		err := p.ParseArgs([]string{"/key:hunter2", "/token:hunter2", "/port:abc"})
+		assert.EqualError(t, err, "invalid value for --port: strconv.Atoi: parsing \"abc\": invalid syntax\n"+
+			"provided args: /key:**** /token:**** /port:abc")
+	})
+
+	t.Run("LenientAssignment", func(t *testing.T) {
+		p := newParser(WithEchoArgsOnError(), WithLenientAssignment(), WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		err := p.ParseArgs([]string{"--key", "=", "hunter2", "--token", "=hunter2", "--port", "=", "abc"})
+		assert.EqualError(t, err, "invalid value for --port: strconv.Atoi: parsing \"abc\": invalid syntax\n"+
+			"provided args: --key = **** --token =**** --port = abc")
+	})
+
+	t.Run("Subcommand", func(t *testing.T) {
+		var password string
+		p := newParser(WithEchoArgsOnError(), WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		p.Command("login", "Log in").String(&password, "password", "Test flag").Redact(func(string) string {
+			return "****"
+		})
+
+		err := p.ParseArgs([]string{"--port=abc", "login", "--password", "hunter2"})
+		assert.EqualError(t, err, "invalid value for --port: strconv.Atoi: parsing \"abc\": invalid syntax\n"+
+			"provided args: --port=abc login --password ****")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		p := newParser(WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		err := p.ParseArgs([]string{"--port=abc"})
+		assert.EqualError(t, err, "invalid value for --port: strconv.Atoi: parsing \"abc\": invalid syntax")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := newParser(WithEchoArgsOnError(), WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		err := p.ParseArgs([]string{"--port=80"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestParserParseDashValue(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "Long", args: []string{"--message", "-x"}},
+		{name: "Short", args: []string{"-m", "-x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				b bool
+				s string
+			)
+			p := New()
+			p.String(&s, "message", "Test flag").Short('m')
+			p.Bool(&b, "extra", "Test flag").Short('x')
+
+			errs := p.parse(tt.args)
+			assert.Empty(t, errs)
+			assert.Equal(t, "-x", s)
+			assert.False(t, b)
+		})
+	}
+
+	t.Run("NegativeNumber", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "offset", "Test flag")
+
+		errs := p.parse([]string{"--offset", "-5"})
+		assert.Empty(t, errs)
+		assert.Equal(t, -5, i)
+	})
+
+	t.Run("FollowedByLongFlag", func(t *testing.T) {
+		var (
+			b bool
+			s string
+		)
+		p := New()
+		p.String(&s, "message", "Test flag")
+		p.Bool(&b, "other", "Test flag")
+
+		errs := p.parse([]string{"--message", "--other"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--message requires a value")
+	})
+
+	t.Run("BoolFollowedByShortFlag", func(t *testing.T) {
+		var (
+			b bool
+			x bool
+		)
+		p := New()
+		p.Bool(&b, "verbose", "Test flag")
+		p.Bool(&x, "extra", "Test flag").Short('x')
+
+		errs := p.parse([]string{"--verbose", "-x"})
+		assert.Empty(t, errs)
+		assert.True(t, b)
+		assert.True(t, x)
+	})
+}
+
+func TestParserParseMissingValue(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "LastArg", args: []string{"--port"}},
+		{name: "FollowedByFlag", args: []string{"--port", "--other"}},
+		{name: "ShortLastArg", args: []string{"-p"}},
+		{name: "ShortFollowedByFlag", args: []string{"-p", "--other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				b bool
+				i int
+			)
+			p := New()
+			p.Int(&i, "port", "Test flag").Short('p')
+			p.Bool(&b, "other", "Test flag")
+
+			errs := p.parse(tt.args)
+			require.Len(t, errs, 1)
+			assert.EqualError(t, errs[0], "--port requires a value")
+		})
+	}
+}
+
+func TestParserDefaultFuncErr(t *testing.T) {
+	newParser := func(host *string, u **url.URL, timeout *time.Duration) *Parser {
+		p := New()
+		p.String(host, "host", "Test flag").Default("localhost")
+		p.URL(u, "endpoint", "Test flag").DefaultFuncErr(func() (*url.URL, error) {
+			return url.Parse("http://" + *host + ":8080")
+		})
+		p.Duration(timeout, "timeout", "Test flag").DefaultFuncErr(func() (time.Duration, error) {
+			return time.ParseDuration("5s")
+		})
+		return p
+	}
+
+	t.Run("Computed", func(t *testing.T) {
+		var (
+			host    string
+			u       *url.URL
+			timeout time.Duration
+		)
+		p := newParser(&host, &u, &timeout)
+
+		err := p.ParseArgs([]string{"--host=example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "http://example.com:8080", u.String())
+		assert.Equal(t, 5*time.Second, timeout)
+	})
+
+	t.Run("NotNeeded", func(t *testing.T) {
+		var (
+			host    string
+			u       *url.URL
+			timeout time.Duration
+		)
+		p := newParser(&host, &u, &timeout)
+
+		err := p.ParseArgs([]string{"--host=bad host", "--endpoint=http://other", "--timeout=1s"})
+		require.NoError(t, err)
+		assert.Equal(t, "http://other", u.String())
+		assert.Equal(t, time.Second, timeout)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		var (
+			host    string
+			u       *url.URL
+			timeout time.Duration
+		)
+		p := newParser(&host, &u, &timeout)
+
+		err := p.ParseArgs([]string{"--host=bad host"})
+		var invalidValueErr *InvalidValueError
+		require.ErrorAs(t, err, &invalidValueErr)
+		assert.Equal(t, "endpoint", invalidValueErr.Name)
+	})
+
+	t.Run("RequiredPanic", func(t *testing.T) {
+		var u *url.URL
+		f := NewURLFlag(&u, "endpoint", "Test flag").Required()
+		assert.Panics(t, func() {
+			f.DefaultFuncErr(func() (*url.URL, error) {
+				return nil, nil
+			})
+		})
+	})
+}
+
+func TestParserParseBoolEqualsForm(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+		err      string
+	}{
+		{args: []string{"--verbose"}, expected: true},
+		{args: []string{"--verbose=true"}, expected: true},
+		{args: []string{"--verbose=1"}, expected: true},
+		{args: []string{"--verbose=false"}, expected: false},
+		{args: []string{"--verbose=0"}, expected: false},
+		{args: []string{"--verbose="}, err: "--verbose requires true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			b := !tt.expected
+			p := New()
+			p.Bool(&b, "verbose", "Test flag")
+
+			errs := p.parse(tt.args)
+			if tt.err != "" {
+				require.Len(t, errs, 1)
+				assert.EqualError(t, errs[0], tt.err)
+				return
+			}
+
+			assert.Empty(t, errs)
+			assert.Equal(t, tt.expected, b)
+		})
+	}
+}
+
+func TestParserParseEmptyEqualsValue(t *testing.T) {
+	t.Run("Int", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "port", "Test flag").Short('p')
+
+		errs := p.parse([]string{"--port="})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--port requires a value after '='")
+
+		errs = p.parse([]string{"-p="})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--port requires a value after '='")
+	})
+
+	t.Run("String", func(t *testing.T) {
+		s := "foo"
+		p := New()
+		p.String(&s, "name", "Test flag")
+
+		errs := p.parse([]string{"--name="})
+		assert.Empty(t, errs)
+		assert.Equal(t, "", s)
+	})
+
+	t.Run("Keyword", func(t *testing.T) {
+		i := 1
+		p := New()
+		p.Int(&i, "port", "Test flag").Keyword("", 0)
+
+		errs := p.parse([]string{"--port="})
+		assert.Empty(t, errs)
+		assert.Equal(t, 0, i)
+	})
+}
+
+func TestParserParseLenientAssignment(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "SeparateEquals", args: []string{"--port", "=", "8080", "--verbose"}},
+		{name: "LeadingEquals", args: []string{"--port", "=8080", "--verbose"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				b bool
+				i int
+			)
+			p := New(WithLenientAssignment())
+			p.Int(&i, "port", "Test flag")
+			p.Bool(&b, "verbose", "Test flag")
+
+			errs := p.parse(tt.args)
+			assert.Empty(t, errs)
+			assert.Equal(t, 8080, i)
+			assert.True(t, b)
+		})
+	}
+
+	t.Run("MissingValue", func(t *testing.T) {
+		var i int
+		p := New(WithLenientAssignment())
+		p.Int(&i, "port", "Test flag")
+
+		errs := p.parse([]string{"--port", "="})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--port requires a value after '='")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "port", "Test flag")
+
+		errs := p.parse([]string{"--port", "=", "8080"})
+		assert.NotEmpty(t, errs)
+	})
+}
+
+func TestParserRestArgs(t *testing.T) {
+	t.Run("NestedFlags", func(t *testing.T) {
+		var (
+			d    time.Duration
+			rest []string
+		)
+		p := New()
+		p.Duration(&d, "timeout", "Test flag")
+		p.RestArgs(&rest)
+
+		errs := p.parse([]string{"--timeout", "5s", "--", "actualcmd", "--timeout=1s", "--", "--help"})
+		assert.Empty(t, errs)
+		assert.Equal(t, 5*time.Second, d)
+		assert.Equal(t, []string{"actualcmd", "--timeout=1s", "--", "--help"}, rest)
+		assert.False(t, p.helpCalled)
+	})
+
+	t.Run("NoTerminator", func(t *testing.T) {
+		var rest []string
+		p := New()
+		p.RestArgs(&rest)
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.Empty(t, rest)
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		var rest []string
+		p := New(WithAppName("test-app"))
+		p.RestArgs(&rest)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+		assert.Contains(t, buf.String(), "Usage: test-app [--help] [-- ARGS...]\n")
+	})
+}
+
+func TestParserParseStringSlice(t *testing.T) {
+	t.Run("Repeated", func(t *testing.T) {
+		var v []string
+		p := New()
+		p.StringSlice(&v, "item", "Test flag").Default([]string{"default"})
+
+		errs := p.parse([]string{"--item=foo,bar", "--item", "baz"})
+		assert.Empty(t, errs)
+		assert.Equal(t, []string{"foo", "bar", "baz"}, v)
+	})
+
+	t.Run("ArgsOverrideEnv", func(t *testing.T) {
+		t.Setenv("ITEM", "foo,bar")
+
+		var v []string
+		p := New()
+		p.StringSlice(&v, "item", "Test flag")
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.Equal(t, []string{"foo", "bar"}, v)
+
+		errs = p.parse([]string{"--item=baz"})
+		assert.Empty(t, errs)
+		assert.Equal(t, []string{"baz"}, v)
+	})
+}
+
+func TestParserParseStringSet(t *testing.T) {
+	t.Run("Repeated", func(t *testing.T) {
+		var v []string
+		p := New()
+		p.StringSet(&v, "allow", "Test flag")
+
+		errs := p.parse([]string{"--allow", "host1", "--allow", "host1", "--allow=host2,host1"})
+		assert.Empty(t, errs)
+		assert.Equal(t, []string{"host1", "host2"}, v)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("ALLOW", "host2,host1,host2")
+
+		var v []string
+		p := New()
+		p.StringSet(&v, "allow", "Test flag")
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.Equal(t, []string{"host2", "host1"}, v)
+	})
+}
+
+func TestParserBoolWithEnvDefault(t *testing.T) {
+	t.Run("EnvDefault", func(t *testing.T) {
+		t.Setenv("FEATURE_DEFAULT", "true")
+
+		var v bool
+		p := New()
+		p.BoolWithEnvDefault(&v, "feature", "Test flag", "FEATURE_DEFAULT")
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.True(t, v)
+	})
+
+	t.Run("ArgsOverride", func(t *testing.T) {
+		t.Setenv("FEATURE_DEFAULT", "true")
+
+		var v bool
+		p := New()
+		p.BoolWithEnvDefault(&v, "feature", "Test flag", "FEATURE_DEFAULT")
+
+		errs := p.parse([]string{"--feature=false"})
+		assert.Empty(t, errs)
+		assert.False(t, v)
+	})
+
+	t.Run("NoEnv", func(t *testing.T) {
+		var v bool
+		p := New()
+		p.BoolWithEnvDefault(&v, "feature", "Test flag", "FEATURE_DEFAULT")
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.False(t, v)
+	})
+
+	t.Run("InvalidEnv", func(t *testing.T) {
+		t.Setenv("FEATURE_DEFAULT", "maybe")
+
+		var v bool
+		p := New()
+		p.BoolWithEnvDefault(&v, "feature", "Test flag", "FEATURE_DEFAULT")
+
+		errs := p.parse(nil)
+		require.Len(t, errs, 1)
+
+		var invalidValueErr *InvalidValueError
+		assert.ErrorAs(t, errs[0], &invalidValueErr)
+	})
+}
+
+func BenchmarkParserParseStringSlice(b *testing.B) {
+	const n = 100000
+
+	b.Run("RepeatedArgs", func(b *testing.B) {
+		args := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			args = append(args, "--item="+strconv.Itoa(i))
+		}
+
+		for i := 0; i < b.N; i++ {
+			var v []string
+			p := New()
+			p.StringSlice(&v, "item", "Test flag")
+
+			if errs := p.parse(args); len(errs) != 0 {
+				b.Fatal(errs)
+			}
+		}
+	})
+
+	b.Run("EnvValue", func(b *testing.B) {
+		items := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			items = append(items, strconv.Itoa(i))
+		}
+		b.Setenv("ITEM", strings.Join(items, ","))
+
+		for i := 0; i < b.N; i++ {
+			var v []string
+			p := New()
+			p.StringSlice(&v, "item", "Test flag")
+
+			if errs := p.parse(nil); len(errs) != 0 {
+				b.Fatal(errs)
+			}
+		}
+	})
+
+	b.Run("RepeatedArgsSet", func(b *testing.B) {
+		args := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			args = append(args, "--item="+strconv.Itoa(i))
+		}
+
+		for i := 0; i < b.N; i++ {
+			var v []string
+			p := New()
+			p.StringSet(&v, "item", "Test flag")
+
+			if errs := p.parse(args); len(errs) != 0 {
+				b.Fatal(errs)
+			}
+			if len(v) != n {
+				b.Fatalf("got %d values, want %d", len(v), n)
+			}
+		}
+	})
+}
+
+func TestParserParseFileRef(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "toggle")
+	err := os.WriteFile(path, []byte("true\n"), 0o600)
+	require.NoError(t, err)
+
+	t.Run("Bool", func(t *testing.T) {
+		var v bool
+		p := New()
+		p.Bool(&v, "feature", "Test flag").AllowFileRef()
+
+		errs := p.parse([]string{"--feature=@" + path})
+		assert.Empty(t, errs)
+		assert.True(t, v)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("FEATURE", "@"+path)
+
+		var v bool
+		p := New()
+		p.Bool(&v, "feature", "Test flag").AllowFileRef()
+
+		errs := p.parse(nil)
+		assert.Empty(t, errs)
+		assert.True(t, v)
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "name", "Test flag")
+
+		errs := p.parse([]string{"--name=@" + path})
+		assert.Empty(t, errs)
+		assert.Equal(t, "@"+path, s)
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		var v bool
+		p := New()
+		p.Bool(&v, "feature", "Test flag").AllowFileRef()
+
+		errs := p.parse([]string{"--feature=@" + path + ".missing"})
+		require.Len(t, errs, 1)
+
+		var invalidValueErr *InvalidValueError
+		assert.ErrorAs(t, errs[0], &invalidValueErr)
+	})
+}
+
+func TestParserParseStdin(t *testing.T) {
+	const pem = "-----BEGIN CERTIFICATE-----\n" +
+		"MIIBszCCAVmgAwIBAgIUExample\n" +
+		"-----END CERTIFICATE-----\n"
+
+	t.Run("PreserveNewlines", func(t *testing.T) {
+		var cert string
+		p := New(WithStdin(strings.NewReader(pem + "\n")))
+		p.String(&cert, "cert", "Test flag").AllowStdin().PreserveNewlines()
+
+		errs := p.parse([]string{"--cert=-"})
+		assert.Empty(t, errs)
+		assert.Equal(t, pem, cert)
+	})
+
+	t.Run("Trimmed", func(t *testing.T) {
+		var cert string
+		p := New(WithStdin(strings.NewReader(pem)))
+		p.String(&cert, "cert", "Test flag").AllowStdin()
+
+		errs := p.parse([]string{"--cert", "-"})
+		assert.Empty(t, errs)
+		assert.Equal(t, strings.TrimSuffix(pem, "\n"), cert)
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		var cert string
+		p := New(WithStdin(strings.NewReader(pem)))
+		p.String(&cert, "cert", "Test flag")
+
+		errs := p.parse([]string{"--cert=-"})
+		assert.Empty(t, errs)
+		assert.Equal(t, "-", cert)
+	})
+}
+
+func TestParserConfigFileFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	err := os.WriteFile(path, []byte(`{"host":"file-host","port":"8080"}`), 0o600)
+	require.NoError(t, err)
+
+	newParser := func(host *string, port *int) *Parser {
+		p := New()
+		p.String(host, "host", "Test flag")
+		p.Int(port, "port", "Test flag")
+		p.ConfigFileFlag("config", func(r io.Reader) error {
+			var m map[string]string
+			if err := json.NewDecoder(r).Decode(&m); err != nil {
+				return err
+			}
+			return p.ApplyMap(m)
+		})
+		return p
+	}
+
+	t.Run("Ordering", func(t *testing.T) {
+		var (
+			host string
+			port int
+		)
+		p := newParser(&host, &port)
+
+		errs := p.parse([]string{"--port=9090", "--config=" + path, "--host=args-host"})
+		assert.Empty(t, errs)
+		assert.Equal(t, "args-host", host)
+		assert.Equal(t, 8080, port)
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		var (
+			host string
+			port int
+		)
+		p := newParser(&host, &port)
+
+		errs := p.parse([]string{"--config=" + path + ".missing"})
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], os.ErrNotExist)
+	})
+
+	t.Run("LoaderError", func(t *testing.T) {
+		var (
+			host string
+			port int
+		)
+		p := newParser(&host, &port)
+
+		errs := p.parse([]string{"--config", os.DevNull})
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], io.EOF)
+	})
+}
+
+func TestParserProfile(t *testing.T) {
+	newParser := func(workers *int, cache *bool) *Parser {
+		p := New()
+		p.Int(workers, "workers", "Test flag").Default(1)
+		p.Bool(cache, "cache", "Test flag")
+		p.Profile("fast", map[string]string{"workers": "8", "cache": "true"})
+		p.Profile("slow", map[string]string{"workers": "1"})
+		return p
+	}
+
+	t.Run("Apply", func(t *testing.T) {
+		var (
+			workers int
+			cache   bool
+		)
+		p := newParser(&workers, &cache)
+
+		errs := p.parse([]string{"--profile=fast"})
+		assert.Empty(t, errs)
+		assert.Equal(t, 8, workers)
+		assert.True(t, cache)
+	})
+
+	t.Run("ExplicitOverride", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--profile=fast", "--workers=2"},
+			{"--workers=2", "--profile=fast"},
+		} {
+			var (
+				workers int
+				cache   bool
+			)
+			p := newParser(&workers, &cache)
+
+			errs := p.parse(args)
+			assert.Empty(t, errs)
+			assert.Equal(t, 2, workers)
+			assert.True(t, cache)
+		}
+	})
+
+	t.Run("UnknownProfile", func(t *testing.T) {
+		var (
+			workers int
+			cache   bool
+		)
+		p := newParser(&workers, &cache)
+
+		errs := p.parse([]string{"--profile=turbo"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], `invalid value for --profile: unknown profile "turbo", available: fast, slow`)
+	})
+
+	t.Run("DuplicatePanic", func(t *testing.T) {
+		var (
+			workers int
+			cache   bool
+		)
+		p := newParser(&workers, &cache)
+
+		assert.Panics(t, func() {
+			p.Profile("fast", nil)
+		})
+	})
+}
+
+func TestParserParseJSON(t *testing.T) {
+	type opts struct {
+		Retries int    `json:"retries"`
+		Timeout string `json:"timeout"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		var v opts
+		p := New()
+		JSONFlag(p, &v, "opts", "Test flag")
+
+		errs := p.parse([]string{`--opts={"retries":3,"timeout":"5s"}`})
+		assert.Empty(t, errs)
+		assert.Equal(t, opts{Retries: 3, Timeout: "5s"}, v)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var v opts
+		p := New()
+		JSONFlag(p, &v, "opts", "Test flag")
+
+		errs := p.parse([]string{`--opts={"retries":`})
+		require.Len(t, errs, 1)
+
+		var invalidValueErr *InvalidValueError
+		require.ErrorAs(t, errs[0], &invalidValueErr)
+		assert.Equal(t, "opts", invalidValueErr.Name)
+		assert.EqualError(t, errs[0], "invalid value for --opts: unexpected end of JSON input")
+	})
+}
+
+func TestParserParsePairs(t *testing.T) {
+	t.Run("OrderAndDuplicates", func(t *testing.T) {
+		var v []Pair
+		p := New()
+		p.Pairs(&v, "header", "Test flag")
+
+		errs := p.parse([]string{"--header=Accept=text/html", "--header", "X-Forwarded-For=10.0.0.1", "--header=Accept=*/*"})
+		assert.Empty(t, errs)
+		assert.Equal(t, []Pair{
+			{Key: "Accept", Value: "text/html"},
+			{Key: "X-Forwarded-For", Value: "10.0.0.1"},
+			{Key: "Accept", Value: "*/*"},
+		}, v)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		var v []Pair
+		p := New()
+		p.Pairs(&v, "header", "Test flag")
+
+		errs := p.parse([]string{"--header=Accept"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "invalid value for --header: expected key=value")
+	})
+
+	t.Run("CommasInValue", func(t *testing.T) {
+		var v []Pair
+		p := New()
+		p.Pairs(&v, "header", "Test flag")
+
+		err := p.ParseArgs([]string{"--header", "Accept=text/html,application/json", "--header=Cache-Control=no-cache, no-store"})
+		require.NoError(t, err)
+		assert.Equal(t, []Pair{
+			{Key: "Accept", Value: "text/html,application/json"},
+			{Key: "Cache-Control", Value: "no-cache, no-store"},
+		}, v)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var v []Pair
+		p := New()
+		p.Pairs(&v, "header", "Test flag")
+
+		err := p.ParseArgs([]string{"--header=a=1", "--header", "b=x,y", "--header=a=2"})
+		require.NoError(t, err)
+
+		args := p.CanonicalArgs()
+		assert.Equal(t, []string{"--header=a=1", "--header=b=x,y", "--header=a=2"}, args)
+
+		buf := bytes.NewBuffer(nil)
+		p.PrintConfig(buf)
+		assert.Equal(t, "--header=a=1\n--header=b=x,y\n--header=a=2\n", buf.String())
+
+		var replayed []Pair
+		p = New()
+		p.Pairs(&replayed, "header", "Test flag")
+
+		err = p.ParseArgs(args)
+		require.NoError(t, err)
+		assert.Equal(t, v, replayed)
+	})
+}
+
+func TestParserParseIgnoreUnknownFlags(t *testing.T) {
+	var i int
+	p := New(WithIgnoreUnknownFlags())
+	p.Int(&i, "test-flag", "Test flag")
+
+	errs := p.parse([]string{"--nonexistent-flag=foo", "-x", "--other-flag", "bar", "--test-flag=10"})
+	assert.Empty(t, errs)
+	assert.Equal(t, 10, i)
+}
+
+func TestParserParsePassThroughOnUnknown(t *testing.T) {
+	args := []string{"--known", "x", "--unknown", "y", "--also", "z"}
+
+	t.Run("PassThrough", func(t *testing.T) {
+		var known, also string
+		p := New(WithPassThroughOnUnknown())
+		p.String(&known, "known", "Test flag")
+		p.String(&also, "also", "Test flag")
+
+		errs := p.parse(args)
+		assert.Empty(t, errs)
+		assert.Equal(t, "x", known)
+		assert.Equal(t, "", also)
+		assert.Equal(t, []string{"--unknown", "y", "--also", "z"}, p.PassThrough())
+	})
+
+	t.Run("IgnoreUnknown", func(t *testing.T) {
+		var known, also string
+		p := New(WithIgnoreUnknownFlags())
+		p.String(&known, "known", "Test flag")
+		p.String(&also, "also", "Test flag")
+
+		errs := p.parse(args)
+		assert.Empty(t, errs)
+		assert.Equal(t, "x", known)
+		assert.Equal(t, "z", also)
+		assert.Empty(t, p.PassThrough())
+	})
+
+	t.Run("UnknownShortFlag", func(t *testing.T) {
+		var known string
+		p := New(WithPassThroughOnUnknown())
+		p.String(&known, "known", "Test flag").Short('k')
+
+		errs := p.parse([]string{"-k", "x", "-u", "y"})
+		assert.Empty(t, errs)
+		assert.Equal(t, "x", known)
+		assert.Equal(t, []string{"-u", "y"}, p.PassThrough())
+	})
+}
+
+func TestParserExtraFlags(t *testing.T) {
+	t.Run("Collected", func(t *testing.T) {
+		var (
+			labels map[string]string
+			set    map[string]string
+		)
+		p := New()
+		p.ExtraFlags("label-", &labels)
+		p.ExtraFlags("set", &set)
+
+		errs := p.parse([]string{"--label-team=infra", "--label-env", "prod", "--set", "foo=bar", "--set=baz=qux"})
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]string{"team": "infra", "env": "prod"}, labels)
+		assert.Equal(t, map[string]string{"foo": "bar", "baz": "qux"}, set)
+	})
+
+	t.Run("NonPrefixedUnknown", func(t *testing.T) {
+		var labels map[string]string
+		p := New()
+		p.ExtraFlags("label-", &labels)
+
+		errs := p.parse([]string{"--label-team=infra", "--unknown=foo"})
+		require.Len(t, errs, 1)
+
+		var unknownFlagErr *UnknownFlagError
+		assert.ErrorAs(t, errs[0], &unknownFlagErr)
+		assert.Equal(t, map[string]string{"team": "infra"}, labels)
+	})
+
+	t.Run("MissingKey", func(t *testing.T) {
+		var set map[string]string
+		p := New()
+		p.ExtraFlags("set", &set)
+
+		errs := p.parse([]string{"--set=foo"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "invalid value for --set: expected key=value")
+	})
+}
+
+func TestParserUnknownArgHandler(t *testing.T) {
+	t.Run("Collect", func(t *testing.T) {
+		var (
+			i       int
+			unknown []string
+		)
+
+		p := New(WithUnknownArgHandler(func(arg string) error {
+			unknown = append(unknown, arg)
+			return nil
+		}))
+		p.Int(&i, "test-flag", "Test flag")
+
+		errs := p.parse([]string{"foo", "--test-flag=10", "bar", "--", "baz"})
+		assert.Empty(t, errs)
+		assert.Equal(t, 10, i)
+		assert.Equal(t, []string{"foo", "bar", "baz"}, unknown)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		var (
+			i       int
+			unknown []string
+		)
+
+		p := New(WithUnknownArgHandler(func(arg string) error {
+			if arg == "bar" {
+				return errors.New("bar is not allowed")
+			}
+			unknown = append(unknown, arg)
+			return nil
+		}))
+		p.Int(&i, "test-flag", "Test flag")
+
+		errs := p.parse([]string{"foo", "bar", "--test-flag=10"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "bar is not allowed")
+		assert.Equal(t, []string{"foo"}, unknown)
+		assert.Equal(t, 0, i)
+	})
+}
+
+func TestParserParseSlashFlags(t *testing.T) {
+	t.Run("Toggle", func(t *testing.T) {
+		var b bool
+		p := New(WithSlashFlags())
+		p.Bool(&b, "verbose", "Test flag")
+
+		errs := p.parse([]string{"/verbose"})
+		assert.Empty(t, errs)
+		assert.True(t, b)
+	})
+
+	t.Run("ColonFormat", func(t *testing.T) {
+		var i int
+		p := New(WithSlashFlags())
+		p.Int(&i, "port", "Test flag")
+
+		errs := p.parse([]string{"/port:8080"})
+		assert.Empty(t, errs)
+		assert.Equal(t, 8080, i)
+	})
+
+	t.Run("MixedStyles", func(t *testing.T) {
+		var (
+			b bool
+			i int
+			s string
+		)
+		p := New(WithSlashFlags())
+		p.Bool(&b, "verbose", "Test flag")
+		p.Int(&i, "port", "Test flag")
+		p.String(&s, "path", "Test flag")
+
+		errs := p.parse([]string{"--verbose", "/port:8080", "--path", "/tmp/foo"})
+		assert.Empty(t, errs)
+		assert.True(t, b)
+		assert.Equal(t, 8080, i)
+		assert.Equal(t, "/tmp/foo", s)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var b bool
+		p := New()
+		p.Bool(&b, "verbose", "Test flag")
+
+		errs := p.parse([]string{"/verbose"})
+		assert.Len(t, errs, 1)
+	})
+}
+
+func TestParserParseShortFlags(t *testing.T) {
+	newParser := func() (*Parser, *bool, *int) {
+		var (
+			b bool
+			i int
+		)
+
+		p := New()
+		p.Bool(&b, "verbose", "Test bool flag").Short('v')
+		p.Int(&i, "offset", "Test int flag").Short('o')
+		return p, &b, &i
+	}
+
+	t.Run("Toggle", func(t *testing.T) {
+		p, b, _ := newParser()
+		errs := p.parse([]string{"-v"})
+		assert.Empty(t, errs)
+		assert.True(t, *b)
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		p, b, i := newParser()
+		errs := p.parse([]string{"-o", "-10", "-v"})
+		assert.Empty(t, errs)
+		assert.True(t, *b)
+		assert.Equal(t, -10, *i)
+	})
+
+	t.Run("ToggleFollowedByShortFlag", func(t *testing.T) {
+		p, b, i := newParser()
+		errs := p.parse([]string{"--verbose", "-o", "10"})
+		assert.Empty(t, errs)
+		assert.True(t, *b)
+		assert.Equal(t, 10, *i)
+	})
+
+	t.Run("UnknownShortFlag", func(t *testing.T) {
+		p, _, _ := newParser()
+		errs := p.parse([]string{"-x"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "unknown flag: -x")
+	})
+}
+
+func TestParserParseShortFlagCluster(t *testing.T) {
+	type flags struct {
+		verbose, quiet, force bool
+		output                string
+	}
+
+	newParser := func() (*Parser, *flags) {
+		f := &flags{}
+		p := New()
+		p.Bool(&f.verbose, "verbose", "Test flag").Short('v')
+		p.Bool(&f.quiet, "quiet", "Test flag").Short('q')
+		p.Bool(&f.force, "force", "Test flag").Short('f')
+		p.String(&f.output, "output", "Test flag").Short('o')
+		return p, f
+	}
+
+	t.Run("Bools", func(t *testing.T) {
+		p, f := newParser()
+		errs := p.parse([]string{"-vqf"})
+		assert.Empty(t, errs)
+		assert.Equal(t, flags{verbose: true, quiet: true, force: true}, *f)
+	})
+
+	t.Run("TrailingValueFlag", func(t *testing.T) {
+		p, f := newParser()
+		errs := p.parse([]string{"-vo", "out.txt", "-q"})
+		assert.Empty(t, errs)
+		assert.Equal(t, flags{verbose: true, quiet: true, output: "out.txt"}, *f)
+	})
+
+	t.Run("TrailingValueFlagMissingValue", func(t *testing.T) {
+		p, _ := newParser()
+		errs := p.parse([]string{"-vo"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--output requires a value")
+	})
+
+	t.Run("ValueFlagMidCluster", func(t *testing.T) {
+		p, _ := newParser()
+		errs := p.parse([]string{"-vof"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "flag -o requires a value, so it must be the last one in -vof")
+	})
+
+	t.Run("UnknownFlag", func(t *testing.T) {
+		p, _ := newParser()
+		errs := p.parse([]string{"-vx"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "unknown flag: -x")
+	})
+
+	t.Run("Sanitized", func(t *testing.T) {
+		var (
+			v, q  bool
+			token string
+		)
+		p := New()
+		p.Bool(&v, "verbose", "Test flag").Short('v')
+		p.Bool(&q, "quiet", "Test flag").Short('q')
+		p.String(&token, "token", "Test flag").Short('o').Redact(func(string) string {
+			return "****"
+		})
+
+		assert.Equal(t, []string{"-vo", "****", "-qf"}, p.sanitizeArgs([]string{"-vo", "secret", "-qf"}))
+	})
+}
+
+func TestParserParseShortFlagValues(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "EqualsSignFormat", args: []string{"-o=out.txt", "-v"}},
+		{name: "AttachedFormat", args: []string{"-oout.txt", "-v"}},
+		{name: "TwoArgsFormat", args: []string{"-o", "out.txt", "-v"}},
+		{name: "BoolEqualsSignFormat", args: []string{"-o", "out.txt", "-v=true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				b bool
+				s string
+			)
+			p := New()
+			p.Bool(&b, "verbose", "Test bool flag").Short('v')
+			p.String(&s, "output", "Test string flag").Short('o')
+
+			errs := p.parse(tt.args)
+			assert.Empty(t, errs)
+			assert.Equal(t, "out.txt", s)
+			assert.True(t, b)
+		})
+	}
+
+	t.Run("BoolEmptyValue", func(t *testing.T) {
+		var b bool
+		p := New()
+		p.Bool(&b, "verbose", "Test bool flag").Short('v')
+
+		errs := p.parse([]string{"-v="})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "--verbose requires true or false")
+	})
+}
+
+func TestParserRegisterShortFlag(t *testing.T) {
+	t.Run("Collision", func(t *testing.T) {
+		var b1, b2 bool
+
+		p := New()
+		p.Bool(&b1, "verbose", "Test flag").Short('v')
+		assert.PanicsWithValue(t, "short flag -v already used by --verbose", func() {
+			p.Bool(&b2, "version-check", "Test flag").Short('v')
+		})
+	})
+
+	t.Run("CollisionOnRegister", func(t *testing.T) {
+		var b1, b2 bool
+
+		p := New()
+		p.Bool(&b1, "verbose", "Test flag").Short('v')
+		f := NewBoolFlag(&b2, "version-check", "Test flag").Short('v')
+		assert.PanicsWithValue(t, "short flag -v already used by --verbose", func() {
+			p.registerFlag("version-check", f)
+		})
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var b bool
+
+		p := New()
+		assert.Panics(t, func() {
+			p.Bool(&b, "verbose", "Test flag").Short('-')
+		})
+	})
+}
+
+func TestParserParseAbbreviations(t *testing.T) {
+	newParser := func(opts ...Option) (*Parser, *bool, *int) {
+		var (
+			b bool
+			i int
+		)
+
+		p := New(opts...)
+		p.Bool(&b, "verbose", "Test bool flag")
+		p.Int(&i, "port", "Test int flag")
+		return p, &b, &i
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		p, _, _ := newParser()
+		errs := p.parse([]string{"--por=10"})
+		assert.Len(t, errs, 1)
+	})
+
+	t.Run("UniquePrefix", func(t *testing.T) {
+		p, b, i := newParser(WithAbbreviations())
+		errs := p.parse([]string{"--por=10", "--verb"})
+		assert.Empty(t, errs)
+		assert.Equal(t, 10, *i)
+		assert.True(t, *b)
+	})
+
+	t.Run("AmbiguousPrefix", func(t *testing.T) {
+		p, _, _ := newParser(WithAbbreviations(), WithAppVersion("1.2.3"))
+		errs := p.parse([]string{"--ver"})
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "ambiguous flag: --ver could be --verbose, --version")
+	})
+
+	t.Run("NoAbbrev", func(t *testing.T) {
+		var s string
+		p, b, i := newParser(WithAbbreviations())
+		p.String(&s, "version-file", "Test string flag").NoAbbrev()
+
+		errs := p.parse([]string{"--ver", "--po=10"})
+		assert.Empty(t, errs)
+		assert.True(t, *b)
+		assert.Equal(t, 10, *i)
+
+		errs = p.parse([]string{"--version-f=foo"})
+		assert.Len(t, errs, 1)
+
+		errs = p.parse([]string{"--version-file=foo"})
+		assert.Empty(t, errs)
+		assert.Equal(t, "foo", s)
+	})
+}
+
+func TestParserParseNameNormalization(t *testing.T) {
+	t.Run("Enabled", func(t *testing.T) {
+		for _, arg := range []string{"--logLevel=debug", "--log_level=debug", "--LOG_LEVEL=debug", "--log-level=debug"} {
+			var s string
+			p := New(WithNameNormalization())
+			p.String(&s, "log-level", "Test flag")
+
+			errs := p.parse([]string{arg})
+			assert.Empty(t, errs, arg)
+			assert.Equal(t, "debug", s, arg)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "log-level", "Test flag")
+
+		errs := p.parse([]string{"--logLevel=debug"})
+		require.Len(t, errs, 1)
+
+		var unknownFlagErr *UnknownFlagError
+		assert.ErrorAs(t, errs[0], &unknownFlagErr)
+	})
+
+	t.Run("HelpKeepsRegisteredName", func(t *testing.T) {
+		var s string
+		p := New(WithNameNormalization(), WithAppName("test-app"))
+		p.String(&s, "logLevel", "Test flag")
+
+		errs := p.parse([]string{"--log-level=debug"})
+		assert.Empty(t, errs)
+		assert.Equal(t, "debug", s)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+		assert.Contains(t, buf.String(), "--logLevel=STRING")
+	})
+}
+
+func TestNormalizeFlagName(t *testing.T) {
+	tests := map[string]string{
+		"log-level": "log-level",
+		"logLevel":  "log-level",
+		"log_level": "log-level",
+		"LOG_LEVEL": "log-level",
+		"HTTPPort":  "http-port",
+		"tlsV2":     "tls-v2",
+	}
+
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, normalizeFlagName(name))
+		})
+	}
+}
+
+func TestParserRequiredWhenEnv(t *testing.T) {
+	newParser := func(s *string) *Parser {
+		p := New()
+		p.String(s, "test-flag", "Test flag").RequiredWhenEnv("APP_ENV", "production")
+		return p
+	}
+
+	t.Run("ConditionMet", func(t *testing.T) {
+		t.Setenv("APP_ENV", "production")
+
+		var s string
+		err := newParser(&s).ParseArgs(nil)
+		var missingFlagErr *MissingFlagError
+		assert.ErrorAs(t, err, &missingFlagErr)
+
+		err = newParser(&s).ParseArgs([]string{"--test-flag=foo"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ConditionUnmet", func(t *testing.T) {
+		t.Setenv("APP_ENV", "staging")
+
+		var s string
+		err := newParser(&s).ParseArgs(nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("LongDescription", func(t *testing.T) {
+		var s string
+		f := NewStringFlag(&s, "test-flag", "Test flag").RequiredWhenEnv("APP_ENV", "production")
+		assert.Equal(t, "  --test-flag=STRING\tTest flag (required when $APP_ENV=production)", f.getLongDescription())
+	})
+
+	t.Run("DefaultPanic", func(t *testing.T) {
+		var s string
+		f := NewStringFlag(&s, "test-flag", "Test flag").RequiredWhenEnv("APP_ENV", "production")
+		assert.Panics(t, func() {
+			f.Default("foo")
+		})
+	})
+}
+
+func TestParserCheckRequiredFlags(t *testing.T) {
+	t.Run("NoRequiredFlags", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag")
+
+		parseErrs := p.parse(nil)
+		require.Empty(t, parseErrs)
+
+		checkErrs := p.checkRequiredFlags()
+		assert.Empty(t, checkErrs)
+	})
+
+	t.Run("RequiredFlagNotSet", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Required()
+
+		parseErrs := p.parse(nil)
+		require.Empty(t, parseErrs)
+
+		checkErrs := p.checkRequiredFlags()
 		assert.Len(t, checkErrs, 1)
 	})
 
-	t.Run("RequiredFlagSet", func(t *testing.T) {
+	t.Run("RequiredFlagSet", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Required()
+
+		parseErrs := p.parse([]string{"--test-flag=10"})
+		require.Empty(t, parseErrs)
+
+		checkErrs := p.checkRequiredFlags()
+		assert.Empty(t, checkErrs)
+	})
+}
+
+func TestParserParseArgs(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Required()
+
+		err := p.ParseArgs([]string{"--test-flag=10"})
+		require.NoError(t, err)
+		assert.Equal(t, 10, i)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Required()
+
+		err := p.ParseArgs([]string{"--help"})
+		assert.ErrorIs(t, err, ErrHelp)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		p := New(WithAppVersion("1.2.3"))
+
+		err := p.ParseArgs([]string{"--version"})
+		assert.ErrorIs(t, err, ErrVersion)
+	})
+
+	t.Run("Info", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Required()
+		p.InfoFlag("license", "Show license", "Apache License 2.0")
+
+		err := p.ParseArgs([]string{"--license"})
+		assert.ErrorIs(t, err, ErrInfo)
+
+		var infoErr *InfoRequestedError
+		require.ErrorAs(t, err, &infoErr)
+		assert.Equal(t, "license", infoErr.Name)
+
+		buf := bytes.NewBuffer(nil)
+		p.printInfo(buf, err)
+		assert.Equal(t, "Apache License 2.0\n", buf.String())
+	})
+
+	t.Run("MissingRequired", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Required()
+
+		err := p.ParseArgs(nil)
+		var missingFlagErr *MissingFlagError
+		require.ErrorAs(t, err, &missingFlagErr)
+		assert.Equal(t, "test-flag", missingFlagErr.Name)
+	})
+}
+
+func TestParserWarnRedundantDefaults(t *testing.T) {
+	t.Run("Redundant", func(t *testing.T) {
+		var i int
+		p := New(WithWarnRedundantDefaults())
+		p.Int(&i, "port", "Test flag").Default(8080)
+
+		err := p.ParseArgs([]string{"--port=8080"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--port is explicitly set to its default value 8080"}, p.Warnings())
+	})
+
+	t.Run("NonRedundant", func(t *testing.T) {
+		var i int
+		p := New(WithWarnRedundantDefaults())
+		p.Int(&i, "port", "Test flag").Default(8080)
+
+		err := p.ParseArgs([]string{"--port=9090"})
+		require.NoError(t, err)
+		assert.Empty(t, p.Warnings())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "port", "Test flag").Default(8080)
+
+		err := p.ParseArgs([]string{"--port=8080"})
+		require.NoError(t, err)
+		assert.Empty(t, p.Warnings())
+	})
+}
+
+func TestParserTimings(t *testing.T) {
+	t.Run("Enabled", func(t *testing.T) {
+		var s string
+		p := New(WithTiming(), WithSecretResolver(fakeSecretResolver{"key": "secret"}))
+		p.String(&s, "test-flag", "Test flag")
+
+		var n int
+		p.Int(&n, "test-int-flag", "Test flag")
+
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"test-int-flag":"1"}`), 0o600))
+		p.ConfigFileFlag("config", func(r io.Reader) error {
+			var values map[string]string
+			if err := json.NewDecoder(r).Decode(&values); err != nil {
+				return err
+			}
+			return p.ApplyMap(values)
+		})
+
+		err := p.LoadDotEnv(strings.NewReader("TEST_FLAG=foo\n"))
+		require.NoError(t, err)
+
+		err = p.LoadLayered(strings.NewReader(`{"test-int-flag": 2}`))
+		require.NoError(t, err)
+
+		err = p.ParseArgs([]string{"--config", path})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		timings := p.Timings()
+		assert.Contains(t, timings, "dotenv")
+		assert.Contains(t, timings, "layered")
+		assert.Contains(t, timings, "env")
+		assert.Contains(t, timings, "config:config")
+		assert.Contains(t, timings, "args")
+		assert.Contains(t, timings, "secrets")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		p := New()
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Nil(t, p.Timings())
+	})
+}
+
+func TestParserConcurrentParse(t *testing.T) {
+	t.Setenv("TEST_INT_FLAG", "10")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 50; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var (
+				i int
+				s string
+			)
+			p := New(WithAppName("test-app"))
+			p.Int(&i, "test-int-flag", "Test flag")
+			p.String(&s, "test-string-flag", "Test flag").Default("foo")
+
+			err := p.ParseArgs([]string{"--test-string-flag=bar"})
+			assert.NoError(t, err)
+			assert.Equal(t, 10, i)
+			assert.Equal(t, "bar", s)
+
+			p.printHelp(io.Discard)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParserConcurrentHelp(t *testing.T) {
+	var i int
+	p := New(WithAppName("test-app"))
+	p.Int(&i, "test-int-flag", "Test flag")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 50; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.printHelp(io.Discard)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParserApplyOrdered(t *testing.T) {
+	t.Run("LaterOverrides", func(t *testing.T) {
+		var (
+			i int
+			s string
+		)
+		p := New()
+		p.Int(&i, "test-int-flag", "Test flag")
+		p.String(&s, "test-string-flag", "Test flag")
+
+		err := p.ApplyOrdered([][2]string{
+			{"test-string-flag", "foo"},
+			{"test-int-flag", "10"},
+			{"test-string-flag", "bar"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 10, i)
+		assert.Equal(t, "bar", s)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag")
+
+		err := p.ApplyOrdered([][2]string{
+			{"test-flag", "abc"},
+			{"nonexistent-flag", "foo"},
+		})
+
+		var (
+			invalidValueErr *InvalidValueError
+			unknownFlagErr  *UnknownFlagError
+		)
+		assert.ErrorAs(t, err, &invalidValueErr)
+		assert.ErrorAs(t, err, &unknownFlagErr)
+	})
+}
+
+func TestParserReloadEnv(t *testing.T) {
+	t.Run("Updated", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "10")
+
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Default(1)
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 10, i)
+
+		t.Setenv("TEST_FLAG", "20")
+		errs := p.ReloadEnv()
+		assert.Empty(t, errs)
+		assert.Equal(t, 20, i)
+	})
+
+	t.Run("DefaultUpdated", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag").Default(1)
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 1, i)
+
+		t.Setenv("TEST_FLAG", "20")
+		errs := p.ReloadEnv()
+		assert.Empty(t, errs)
+		assert.Equal(t, 20, i)
+	})
+
+	t.Run("ArgsNotOverridden", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "10")
+
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag")
+
+		err := p.ParseArgs([]string{"--test-flag=5"})
+		require.NoError(t, err)
+
+		t.Setenv("TEST_FLAG", "20")
+		errs := p.ReloadEnv()
+		assert.Empty(t, errs)
+		assert.Equal(t, 5, i)
+	})
+
+	t.Run("InvalidKeepsValues", func(t *testing.T) {
+		t.Setenv("TEST_INT_FLAG", "10")
+		t.Setenv("TEST_STRING_FLAG", "foo")
+
+		var (
+			i int
+			s string
+		)
+		p := New()
+		p.Int(&i, "test-int-flag", "Test flag")
+		p.String(&s, "test-string-flag", "Test flag")
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+
+		t.Setenv("TEST_INT_FLAG", "abc")
+		t.Setenv("TEST_STRING_FLAG", "bar")
+		errs := p.ReloadEnv()
+		require.Len(t, errs, 1)
+
+		var invalidValueErr *InvalidValueError
+		assert.ErrorAs(t, errs[0], &invalidValueErr)
+		assert.Equal(t, 10, i)
+		assert.Equal(t, "foo", s)
+	})
+
+	t.Run("Interpolated", func(t *testing.T) {
+		t.Setenv("DATA_DIR", "/d")
+		t.Setenv("CACHE_DIR", "${data-dir}/c")
+
+		var dataDir, cacheDir string
+		p := New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Interpolate()
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "/d/c", cacheDir)
+
+		errs := p.ReloadEnv()
+		require.Empty(t, errs)
+		assert.Equal(t, "/d/c", cacheDir)
+
+		t.Setenv("DATA_DIR", "/e")
+		errs = p.ReloadEnv()
+		require.Empty(t, errs)
+		assert.Equal(t, "/e", dataDir)
+		assert.Equal(t, "/e/c", cacheDir)
+	})
+
+	t.Run("InterpolatedDefault", func(t *testing.T) {
+		t.Setenv("DATA_DIR", "/d")
+
+		var dataDir, cacheDir string
+		p := New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Default("${data-dir}/c").Interpolate()
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "/d/c", cacheDir)
+
+		t.Setenv("DATA_DIR", "/e")
+		errs := p.ReloadEnv()
+		require.Empty(t, errs)
+		assert.Equal(t, "/e/c", cacheDir)
+	})
+
+	t.Run("InterpolationFailureKeepsValues", func(t *testing.T) {
+		t.Setenv("DATA_DIR", "/d")
+		t.Setenv("CACHE_DIR", "${data-dir}/c")
+
+		var dataDir, cacheDir string
+		p := New()
+		p.String(&dataDir, "data-dir", "Data dir")
+		p.String(&cacheDir, "cache-dir", "Cache dir").Interpolate()
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+
+		t.Setenv("DATA_DIR", "/e")
+		t.Setenv("CACHE_DIR", "${nonexistent-flag}/c")
+		errs := p.ReloadEnv()
+		require.NotEmpty(t, errs)
+		assert.Equal(t, "/d", dataDir)
+		assert.Equal(t, "/d/c", cacheDir)
+
+		t.Setenv("CACHE_DIR", "${data-dir}/c")
+		errs = p.ReloadEnv()
+		require.Empty(t, errs)
+		assert.Equal(t, "/e/c", cacheDir)
+	})
+}
+
+func TestParserApplyMap(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		var (
+			i int
+			s string
+		)
+		p := New()
+		p.Int(&i, "test-int-flag", "Test flag")
+		p.String(&s, "test-string-flag", "Test flag")
+
+		err := p.ApplyMap(map[string]string{
+			"test-int-flag":    "10",
+			"test-string-flag": "foo",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 10, i)
+		assert.Equal(t, "foo", s)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var i int
+		p := New()
+		p.Int(&i, "test-flag", "Test flag")
+
+		err := p.ApplyMap(map[string]string{
+			"test-flag":        "abc",
+			"nonexistent-flag": "foo",
+		})
+
+		var (
+			invalidValueErr *InvalidValueError
+			unknownFlagErr  *UnknownFlagError
+		)
+		assert.ErrorAs(t, err, &invalidValueErr)
+		assert.ErrorAs(t, err, &unknownFlagErr)
+	})
+
+	t.Run("IgnoreUnknown", func(t *testing.T) {
 		var i int
+		p := New(WithIgnoreUnknownFlags())
+		p.Int(&i, "test-flag", "Test flag")
+
+		err := p.ApplyMap(map[string]string{
+			"test-flag":        "10",
+			"nonexistent-flag": "foo",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 10, i)
+	})
+}
+
+func TestParserOnParsed(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var (
+			logLevel string
+			calls    []string
+		)
+
+		p := New()
+		p.String(&logLevel, "log-level", "Test flag").Default("info")
+		p.OnParsed(func(*Parser) {
+			calls = append(calls, "first:"+logLevel)
+		})
+		p.OnParsed(func(*Parser) {
+			calls = append(calls, "second:"+logLevel)
+		})
+
+		err := p.ParseArgs([]string{"--log-level=debug"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first:debug", "second:debug"}, calls)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		var (
+			i      int
+			called bool
+		)
+
 		p := New()
 		p.Int(&i, "test-flag", "Test flag").Required()
+		p.OnParsed(func(*Parser) {
+			called = true
+		})
 
-		parseErrs := p.parse([]string{"--test-flag=10"})
-		require.Empty(t, parseErrs)
+		err := p.ParseArgs(nil)
+		require.Error(t, err)
+		assert.False(t, called)
+	})
+}
 
-		checkErrs := p.checkRequiredFlags()
-		assert.Empty(t, checkErrs)
+func TestParserMustBeParsed(t *testing.T) {
+	var i int
+	p := New()
+	p.Int(&i, "test-flag", "Test flag").Required()
+
+	assert.Panics(t, p.MustBeParsed)
+
+	err := p.ParseArgs(nil)
+	require.Error(t, err)
+	assert.Panics(t, p.MustBeParsed)
+
+	err = p.ParseArgs([]string{"--test-flag=10"})
+	require.NoError(t, err)
+	assert.NotPanics(t, p.MustBeParsed)
+}
+
+func TestParserAtomicParse(t *testing.T) {
+	t.Run("Failure", func(t *testing.T) {
+		var (
+			i = 1
+			s = "foo"
+		)
+
+		p := New(WithAtomicParse())
+		p.Int(&i, "test-int-flag", "Test int flag").Required()
+		p.String(&s, "test-string-flag", "Test string flag").Default("bar")
+
+		err := p.ParseArgs([]string{"--test-string-flag=baz", "--nonexistent-flag"})
+		require.Error(t, err)
+		assert.Equal(t, 1, i)
+		assert.Equal(t, "foo", s)
+	})
+
+	t.Run("RequiredFailure", func(t *testing.T) {
+		var (
+			i = 1
+			s = "foo"
+		)
+
+		p := New(WithAtomicParse())
+		p.Int(&i, "test-int-flag", "Test int flag").Required()
+		p.String(&s, "test-string-flag", "Test string flag")
+
+		err := p.ParseArgs([]string{"--test-string-flag=baz"})
+		require.Error(t, err)
+		assert.Equal(t, 1, i)
+		assert.Equal(t, "foo", s)
+	})
+
+	t.Run("PositionalFailure", func(t *testing.T) {
+		var (
+			i   = 1
+			src = "foo"
+			dst = "bar"
+		)
+
+		p := New(WithAtomicParse())
+		p.Int(&i, "test-int-flag", "Test int flag").Required()
+		p.Arg(&src, "SRC", "Source file")
+		p.Arg(&dst, "DST", "Destination file").Default(".")
+
+		err := p.ParseArgs([]string{"file"})
+		require.Error(t, err)
+		assert.Equal(t, "foo", src)
+		assert.Equal(t, "bar", dst)
+	})
+
+	t.Run("RestArgsFailure", func(t *testing.T) {
+		var (
+			s    = "foo"
+			rest = []string{"old"}
+		)
+
+		p := New(WithAtomicParse())
+		p.String(&s, "name", "Test string flag").Required()
+		p.RestArgs(&rest)
+
+		err := p.ParseArgs([]string{"--", "a", "b"})
+		require.Error(t, err)
+		assert.Equal(t, []string{"old"}, rest)
+	})
+
+	t.Run("ExtraFlagsFailure", func(t *testing.T) {
+		var (
+			s      = "foo"
+			extras = map[string]string{"old": "value"}
+		)
+
+		p := New(WithAtomicParse())
+		p.String(&s, "name", "Test string flag").Required()
+		p.ExtraFlags("x-", &extras)
+
+		err := p.ParseArgs([]string{"--x-foo=bar"})
+		require.Error(t, err)
+		assert.Equal(t, map[string]string{"old": "value"}, extras)
+
+		var unset map[string]string
+		p = New(WithAtomicParse())
+		p.String(&s, "name", "Test string flag").Required()
+		p.ExtraFlags("x-", &unset)
+
+		err = p.ParseArgs([]string{"--x-foo=bar"})
+		require.Error(t, err)
+		assert.Nil(t, unset)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var (
+			i = 1
+			s = "foo"
+		)
+
+		p := New(WithAtomicParse())
+		p.Int(&i, "test-int-flag", "Test int flag").Required()
+		p.String(&s, "test-string-flag", "Test string flag")
+
+		err := p.ParseArgs([]string{"--test-int-flag=2", "--test-string-flag=baz"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, i)
+		assert.Equal(t, "baz", s)
+	})
+}
+
+func TestParserSameSource(t *testing.T) {
+	newParser := func(user, password *string) *Parser {
+		p := New()
+		p.String(user, "test-user", "Test user")
+		p.String(password, "test-password", "Test password")
+		p.SameSource("test-user", "test-password")
+		return p
+	}
+
+	t.Run("AllEnv", func(t *testing.T) {
+		t.Setenv("TEST_USER", "foo")
+		t.Setenv("TEST_PASSWORD", "bar")
+
+		var user, password string
+		err := newParser(&user, &password).ParseArgs(nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("AllArgs", func(t *testing.T) {
+		var user, password string
+		err := newParser(&user, &password).ParseArgs([]string{"--test-user=foo", "--test-password=bar"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Mixed", func(t *testing.T) {
+		t.Setenv("TEST_PASSWORD", "bar")
+
+		var user, password string
+		err := newParser(&user, &password).ParseArgs([]string{"--test-user=foo"})
+		assert.EqualError(t, err, "flags --test-user and --test-password must be set from the same source, not mixing command line and environment")
+	})
+
+	t.Run("UnknownFlagPanic", func(t *testing.T) {
+		p := New()
+		assert.Panics(t, func() {
+			p.SameSource("nonexistent-flag")
+		})
+	})
+}
+
+func TestParserDefaultOverridesEnv(t *testing.T) {
+	t.Run("DefaultWins", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "from-env")
+
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").Default("from-default").DefaultOverridesEnv()
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "from-default", s)
+	})
+
+	t.Run("NoDefault", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "from-env")
+
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").DefaultOverridesEnv()
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "from-env", s)
+	})
+
+	t.Run("ArgsWin", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "from-env")
+
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").Default("from-default").DefaultOverridesEnv()
+
+		err := p.ParseArgs([]string{"--test-flag=from-args"})
+		require.NoError(t, err)
+		assert.Equal(t, "from-args", s)
+	})
+}
+
+func TestParserEnvSnapshot(t *testing.T) {
+	t.Run("Enabled", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "foo")
+
+		var s string
+		p := New(WithEnvSnapshot())
+		p.String(&s, "test-flag", "Test flag")
+
+		t.Setenv("TEST_FLAG", "bar")
+
+		errs := p.parse(nil)
+		require.Empty(t, errs)
+		assert.Equal(t, "foo", s)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Setenv("TEST_FLAG", "foo")
+
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag")
+
+		t.Setenv("TEST_FLAG", "bar")
+
+		errs := p.parse(nil)
+		require.Empty(t, errs)
+		assert.Equal(t, "bar", s)
+	})
+}
+
+func TestParserBoolEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+		valid    bool
+	}{
+		{value: "1", expected: true, valid: true},
+		{value: "t", expected: true, valid: true},
+		{value: "T", expected: true, valid: true},
+		{value: "true", expected: true, valid: true},
+		{value: "TRUE", expected: true, valid: true},
+		{value: "True", expected: true, valid: true},
+		{value: "0", expected: false, valid: true},
+		{value: "f", expected: false, valid: true},
+		{value: "F", expected: false, valid: true},
+		{value: "false", expected: false, valid: true},
+		{value: "FALSE", expected: false, valid: true},
+		{value: "False", expected: false, valid: true},
+		{value: "2", valid: false},
+		{value: "yes", valid: false},
+		{value: "on", valid: false},
+		{value: "tRUE", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TEST_FLAG", tt.value)
+
+			b := !tt.expected
+			p := New()
+			p.Bool(&b, "test-flag", "Test flag")
+
+			errs := p.parse(nil)
+			if !tt.valid {
+				assert.Len(t, errs, 1)
+				return
+			}
+
+			assert.Empty(t, errs)
+			assert.Equal(t, tt.expected, b)
+		})
+	}
+}
+
+func TestParserBoolWords(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{value: "yes", expected: true},
+		{value: "On", expected: true},
+		{value: "no", expected: false},
+		{value: "OFF", expected: false},
+		{value: "1", expected: true},
+		{value: "false", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TEST_FLAG", tt.value)
+
+			b := !tt.expected
+			p := New(WithBoolWords([]string{"yes", "on"}, []string{"no", "off"}))
+			p.Bool(&b, "test-flag", "Test flag")
+
+			errs := p.parse(nil)
+			assert.Empty(t, errs)
+			assert.Equal(t, tt.expected, b)
+		})
+	}
+}
+
+func TestParserStrictMode(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		var (
+			s     string
+			items []string
+		)
+		p := New(WithStrictMode())
+		p.String(&s, "test-flag", "Test flag").Required()
+		p.StringSlice(&items, "item", "Test flag")
+
+		err := p.ParseArgs([]string{"--test-flag=foo", "--item=a", "--item=b"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, items)
+	})
+
+	t.Run("UnknownFlag", func(t *testing.T) {
+		p := New(WithStrictMode())
+
+		err := p.ParseArgs([]string{"--nonexistent-flag"})
+		var unknownFlagErr *UnknownFlagError
+		assert.ErrorAs(t, err, &unknownFlagErr)
+	})
+
+	t.Run("ExtraArgs", func(t *testing.T) {
+		p := New(WithStrictMode())
+
+		err := p.ParseArgs([]string{"abc"})
+		var unexpectedArgsErr *UnexpectedArgumentsError
+		assert.ErrorAs(t, err, &unexpectedArgsErr)
+	})
+
+	t.Run("UnknownEnv", func(t *testing.T) {
+		t.Setenv("TESTAPP_PORTT", "8080")
+
+		var i int
+		p := New(WithEnvVarPrefix("TESTAPP_"), WithStrictMode())
+		p.Int(&i, "port", "Test flag")
+
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "unknown environment variable: $TESTAPP_PORTT")
+	})
+
+	t.Run("RepeatedFlag", func(t *testing.T) {
+		var i int
+		p := New(WithStrictMode())
+		p.Int(&i, "test-flag", "Test flag").Short('t')
+
+		err := p.ParseArgs([]string{"--test-flag=1", "-t", "2"})
+		assert.EqualError(t, err, "--test-flag is set more than once")
+	})
+
+	t.Run("EmptyRequired", func(t *testing.T) {
+		var s string
+		p := New(WithStrictMode())
+		p.String(&s, "test-flag", "Test flag").Required()
+
+		err := p.ParseArgs([]string{"--test-flag="})
+		assert.EqualError(t, err, "invalid value for --test-flag: must not be empty")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		var (
+			i int
+			s string
+		)
+		p := New()
+		p.Int(&i, "test-int-flag", "Test flag")
+		p.String(&s, "test-string-flag", "Test flag").Required()
+
+		err := p.ParseArgs([]string{"--test-int-flag=1", "--test-int-flag=2", "--test-string-flag="})
+		require.NoError(t, err)
+		assert.Equal(t, 2, i)
+	})
+}
+
+func TestParserValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").Required().Hidden()
+
+		assert.NoError(t, p.Validate())
+	})
+
+	t.Run("RequiredHiddenWithoutEnv", func(t *testing.T) {
+		var s string
+		p := New(WithoutAutoEnv())
+		p.String(&s, "test-flag", "Test flag").Required().Hidden()
+
+		err := p.Validate()
+		assert.EqualError(t, err, "flag --test-flag is required and hidden, but has no env var or secret to be set from")
+	})
+
+	t.Run("RequiredHiddenFromSecret", func(t *testing.T) {
+		var s string
+		p := New(WithoutAutoEnv())
+		p.String(&s, "test-flag", "Test flag").Required().Hidden().FromSecret("test/key")
+
+		assert.NoError(t, p.Validate())
+	})
+}
+
+func TestParserAuditHelp(t *testing.T) {
+	t.Run("Clean", func(t *testing.T) {
+		var (
+			s string
+			b bool
+		)
+		p := New()
+		p.String(&s, "test-string-flag", "Test flag").Required()
+		p.Bool(&b, "test-bool-flag", "Test flag").Hidden()
+
+		assert.Empty(t, p.AuditHelp())
+	})
+
+	t.Run("Undocumented", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "test-flag", " ")
+
+		assert.Equal(t, []string{"--test-flag has no help message"}, p.AuditHelp())
+	})
+
+	t.Run("HiddenRequired", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").Required().Hidden()
+
+		assert.Equal(t, []string{"--test-flag is required, but hidden from help"}, p.AuditHelp())
+	})
+
+	t.Run("MissingPlaceholder", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag").Placeholder("")
+
+		assert.Equal(t, []string{"--test-flag has no value placeholder"}, p.AuditHelp())
+	})
+}
+
+type fakeSecretResolver map[string]string
+
+// countingSecretResolver records the keys it is asked to resolve.
+type countingSecretResolver struct {
+	fakeSecretResolver
+	calls []string
+}
+
+func (r *countingSecretResolver) Resolve(key string) (string, error) {
+	r.calls = append(r.calls, key)
+	return r.fakeSecretResolver.Resolve(key)
+}
+
+func (r fakeSecretResolver) Resolve(key string) (string, error) {
+	val, ok := r[key]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return val, nil
+}
+
+func TestParserFromSecret(t *testing.T) {
+	resolver := fakeSecretResolver{"db/password": "s3cr3t"}
+
+	t.Run("Resolved", func(t *testing.T) {
+		var s string
+		p := New(WithSecretResolver(resolver))
+		p.String(&s, "db-password", "Test flag").FromSecret("db/password").Default("default")
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t", s)
+	})
+
+	t.Run("EnvWins", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "from-env")
+
+		var s string
+		p := New(WithSecretResolver(resolver))
+		p.String(&s, "db-password", "Test flag").FromSecret("db/password")
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "from-env", s)
+	})
+
+	t.Run("ArgsWin", func(t *testing.T) {
+		var s string
+		p := New(WithSecretResolver(resolver))
+		p.String(&s, "db-password", "Test flag").FromSecret("db/password")
+
+		err := p.ParseArgs([]string{"--db-password=from-args"})
+		require.NoError(t, err)
+		assert.Equal(t, "from-args", s)
+	})
+
+	t.Run("ResolvedLazily", func(t *testing.T) {
+		var a, b string
+		r := &countingSecretResolver{fakeSecretResolver: fakeSecretResolver{"a": "secret-a", "b": "secret-b"}}
+		p := New(WithSecretResolver(r))
+		p.String(&a, "a", "Test flag").FromSecret("a")
+		p.String(&b, "b", "Test flag").FromSecret("b")
+
+		err := p.ParseArgs([]string{"--a=from-args"})
+		require.NoError(t, err)
+		assert.Equal(t, "from-args", a)
+		assert.Equal(t, "secret-b", b)
+		assert.Equal(t, []string{"b"}, r.calls)
+
+		r.calls = nil
+		err = p.ParseArgs([]string{"--help"})
+		assert.ErrorIs(t, err, ErrHelp)
+		assert.Empty(t, r.calls)
+	})
+
+	t.Run("Masked", func(t *testing.T) {
+		var s string
+		p := New(WithSecretResolver(resolver))
+		p.String(&s, "db-password", "Test flag").FromSecret("db/password")
+
+		err := p.ParseArgs(nil)
+		require.NoError(t, err)
+
+		buf := bytes.NewBuffer(nil)
+		p.ExplainConfig(buf)
+		assert.Equal(t, "FLAG           VALUE  SOURCE\n"+
+			"--db-password  ****   secret\n", buf.String())
+
+		buf.Reset()
+		p.PrintConfig(buf)
+		assert.Equal(t, "--db-password=****\n", buf.String())
+
+		err = p.ParseArgs([]string{"--db-password=from-args"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--db-password=****"}, p.CanonicalArgs())
+	})
+
+	t.Run("ResolverError", func(t *testing.T) {
+		var s string
+		p := New(WithSecretResolver(resolver))
+		p.String(&s, "db-password", "Test flag").FromSecret("db/nonexistent")
+
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "resolving secret for --db-password: secret not found")
+	})
+
+	t.Run("NoResolver", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "db-password", "Test flag").FromSecret("db/password")
+
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "no secret resolver configured for --db-password")
+	})
+}
+
+type fakeHostResolver map[string][]string
+
+func (r fakeHostResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+// hangingHostResolver never resolves, returning once the context is done.
+type hangingHostResolver struct{}
+
+func (hangingHostResolver) LookupHost(ctx context.Context, _ string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestParserRequireResolvableHost(t *testing.T) {
+	resolver := fakeHostResolver{"db.internal": {"10.0.0.1"}}
+
+	t.Run("Resolvable", func(t *testing.T) {
+		var u *url.URL
+		p := New(WithHostResolver(resolver))
+		p.URL(&u, "db-url", "Test flag").RequireResolvableHost()
+
+		err := p.ParseArgs([]string{"--db-url=postgres://db.internal:5432/app"})
+		require.NoError(t, err)
+		assert.Equal(t, "db.internal", u.Hostname())
+	})
+
+	t.Run("Unresolvable", func(t *testing.T) {
+		var u *url.URL
+		p := New(WithHostResolver(resolver))
+		p.URL(&u, "db-url", "Test flag").RequireResolvableHost()
+
+		err := p.ParseArgs([]string{"--db-url=postgres://db.invalid:5432/app"})
+		assert.EqualError(t, err, "invalid value for --db-url: host db.invalid does not resolve: no such host")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		var u *url.URL
+		p := New(WithHostResolver(hangingHostResolver{}))
+		p.URL(&u, "db-url", "Test flag").RequireResolvableHost()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := p.ParseArgsContext(ctx, []string{"--db-url=postgres://db.internal:5432/app"})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("CanceledInSubcommand", func(t *testing.T) {
+		var u *url.URL
+		p := New(WithHostResolver(hangingHostResolver{}))
+		p.Command("migrate", "Run migrations").URL(&u, "db-url", "Test flag").RequireResolvableHost()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := p.ParseArgsContext(ctx, []string{"migrate", "--db-url=postgres://db.internal:5432/app"})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("NonURLPanic", func(t *testing.T) {
+		var s string
+		p := New()
+		assert.Panics(t, func() {
+			p.String(&s, "host", "Test flag").RequireResolvableHost()
+		})
 	})
 }
@@ -0,0 +1,178 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Arg registers a positional argument. Positional arguments are filled in
+// the registration order by the non-flag arguments, including the ones
+// following the -- terminator. They are optional unless marked via the
+// .Required() method, and the required ones must precede the optional ones.
+// Modifiers specific to flags, like .Short() or .Env(), panic for them.
+func (p *Parser) Arg(target *string, name, description string) *Flag[string] {
+	f := NewStringFlag(target, name, description)
+	f.positional = true
+	p.registerArg(f)
+	return f
+}
+
+func (p *Parser) IntArg(target *int, name, description string) *Flag[int] {
+	f := NewIntFlag(target, name, description)
+	f.positional = true
+	p.registerArg(f)
+	return f
+}
+
+func (p *Parser) FloatArg(target *float64, bitSize int, name, description string) *Flag[float64] {
+	f := NewFloatFlag(target, bitSize, name, description)
+	f.positional = true
+	p.registerArg(f)
+	return f
+}
+
+func (p *Parser) DurationArg(target *time.Duration, name, description string) *Flag[time.Duration] {
+	f := NewDurationFlag(target, name, description)
+	f.positional = true
+	p.registerArg(f)
+	return f
+}
+
+func (p *Parser) registerArg(f flag) {
+	for _, arg := range p.args {
+		if arg.getName() == f.getName() {
+			panic(fmt.Sprintf("argument with name %s is already registered", f.getName()))
+		}
+	}
+
+	p.args = append(p.args, f)
+	f.bind(p)
+}
+
+// setNextArg fills the next positional argument, reporting false if all of
+// them are already filled.
+func (p *Parser) setNextArg(value string) (bool, error) {
+	if p.argIndex == len(p.args) {
+		return false, nil
+	}
+
+	f := p.args[p.argIndex]
+	p.argIndex++
+	p.argCount++
+
+	if err := f.setValueFromString(value); err != nil {
+		var invalidValueErr *InvalidValueError
+		if errors.As(err, &invalidValueErr) {
+			err = invalidValueErr.Err
+		}
+		return true, fmt.Errorf("invalid value for argument %s: %w", f.getName(), err)
+	}
+
+	return true, nil
+}
+
+func (p *Parser) checkRequiredArgs() []error {
+	var checkErrs []error
+
+	for _, arg := range p.args {
+		if arg.isRequired() && !arg.isSet() {
+			checkErrs = append(checkErrs, &MissingArgumentError{Name: arg.getName()})
+		}
+	}
+
+	return checkErrs
+}
+
+// PositionalRange limits the number of positional arguments, i.e. the
+// declared ones, the ones passed to the unknown argument handler and the
+// rest arguments. A max of -1 means no upper limit.
+func (p *Parser) PositionalRange(min, max int) {
+	if min < 0 || (max != -1 && max < min) {
+		panic(fmt.Sprintf("invalid positional arguments range: %d to %d", min, max))
+	}
+
+	p.argRange = &[2]int{min, max}
+}
+
+func (p *Parser) checkPositionalRange() error {
+	if p.argRange == nil {
+		return nil
+	}
+
+	min, max := p.argRange[0], p.argRange[1]
+	switch {
+	case max == -1 && p.argCount < min:
+		return fmt.Errorf("expected at least %d arguments, got %d", min, p.argCount)
+	case max != -1 && (p.argCount < min || p.argCount > max):
+		return fmt.Errorf("expected between %d and %d arguments, got %d", min, max, p.argCount)
+	}
+
+	return nil
+}
+
+func (p *Parser) validateArgs() []error {
+	var (
+		errs     []error
+		optional string
+	)
+
+	for _, arg := range p.args {
+		switch {
+		case !arg.isRequired():
+			if optional == "" {
+				optional = arg.getName()
+			}
+		case optional != "":
+			errs = append(errs, fmt.Errorf("required argument %s follows optional argument %s", arg.getName(), optional))
+		}
+	}
+
+	return errs
+}
+
+func (p *Parser) printArgsUsage(w io.Writer) {
+	for _, arg := range p.args {
+		if arg.isRequired() {
+			fmt.Fprintf(w, " %s", arg.getName())
+		} else {
+			fmt.Fprintf(w, " [%s]", arg.getName())
+		}
+	}
+}
+
+func (p *Parser) printArgsHelp(w io.Writer) {
+	if len(p.args) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "\n")
+	fmt.Fprintln(w, "Arguments:")
+
+	tw := p.newHelpTabWriter(w)
+	for _, arg := range p.args {
+		fmt.Fprintf(tw, "  %s\t%s", arg.getName(), arg.getHelpMessage())
+		if arg.isRequired() {
+			fmt.Fprintf(tw, " %s", p.requiredLabel)
+		} else if def, ok := arg.getDefaultValueString(); ok {
+			fmt.Fprintf(tw, " "+p.defaultLabelFormat, def)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
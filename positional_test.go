@@ -0,0 +1,238 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserArg(t *testing.T) {
+	type cpArgs struct {
+		src, dst  string
+		recursive bool
+	}
+
+	newParser := func() (*Parser, *cpArgs) {
+		a := &cpArgs{}
+		p := New(WithAppName("cp"))
+		p.Bool(&a.recursive, "recursive", "Copy directories recursively").Short('r')
+		p.Arg(&a.src, "SRC", "Source file").Required()
+		p.Arg(&a.dst, "DST", "Destination file").Default(".")
+		return p, a
+	}
+
+	t.Run("Both", func(t *testing.T) {
+		p, a := newParser()
+		err := p.ParseArgs([]string{"-r", "a.txt", "b.txt"})
+		require.NoError(t, err)
+		assert.Equal(t, cpArgs{src: "a.txt", dst: "b.txt", recursive: true}, *a)
+	})
+
+	t.Run("FlagsInterleaved", func(t *testing.T) {
+		p, a := newParser()
+		err := p.ParseArgs([]string{"a.txt", "--recursive", "b.txt"})
+		require.NoError(t, err)
+		assert.Equal(t, cpArgs{src: "a.txt", dst: "b.txt", recursive: true}, *a)
+	})
+
+	t.Run("OptionalDefault", func(t *testing.T) {
+		p, a := newParser()
+		err := p.ParseArgs([]string{"a.txt"})
+		require.NoError(t, err)
+		assert.Equal(t, cpArgs{src: "a.txt", dst: "."}, *a)
+	})
+
+	t.Run("AfterTerminator", func(t *testing.T) {
+		p, a := newParser()
+		err := p.ParseArgs([]string{"--", "--weird-name", "b.txt"})
+		require.NoError(t, err)
+		assert.Equal(t, cpArgs{src: "--weird-name", dst: "b.txt"}, *a)
+	})
+
+	t.Run("MissingRequired", func(t *testing.T) {
+		p, _ := newParser()
+		err := p.ParseArgs([]string{"-r"})
+		var missingArgErr *MissingArgumentError
+		require.ErrorAs(t, err, &missingArgErr)
+		assert.EqualError(t, err, "missing required argument: SRC")
+	})
+
+	t.Run("TooMany", func(t *testing.T) {
+		p, _ := newParser()
+		err := p.ParseArgs([]string{"a.txt", "b.txt", "c.txt"})
+		assert.EqualError(t, err, "unexpected argument: c.txt")
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		var (
+			count   int
+			timeout time.Duration
+		)
+		p := New()
+		p.IntArg(&count, "COUNT", "Count").Required()
+		p.DurationArg(&timeout, "TIMEOUT", "Timeout")
+
+		err := p.ParseArgs([]string{"3", "5s"})
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+		assert.Equal(t, 5*time.Second, timeout)
+
+		err = p.ParseArgs([]string{"three"})
+		assert.EqualError(t, err, `invalid value for argument COUNT: strconv.Atoi: parsing "three": invalid syntax`)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		p, _ := newParser()
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+
+		assert.Equal(t, "Usage: cp [--help] [--recursive] SRC [DST]\n\n"+
+			"Flags:\n"+
+			"      --help       Show help message\n"+
+			"  -r, --recursive  Copy directories recursively [$RECURSIVE]\n"+
+			"\n"+
+			"Arguments:\n"+
+			"  SRC  Source file (required)\n"+
+			"  DST  Destination file (default: .)\n", buf.String())
+	})
+
+	t.Run("HelpTopic", func(t *testing.T) {
+		p, a := newParser()
+
+		err := p.ParseArgs([]string{"--help", "recursive"})
+		assert.ErrorIs(t, err, ErrHelp)
+		assert.Empty(t, a.src)
+
+		buf := bytes.NewBuffer(nil)
+		p.printHelp(buf)
+		assert.Equal(t, "--recursive\n"+
+			"  Copy directories recursively\n"+
+			"  Environment: $RECURSIVE\n", buf.String())
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		p, _ := newParser()
+
+		buf := bytes.NewBuffer(nil)
+		p.ExplainArgs([]string{"-r", "a.txt", "--", "b.txt", "c.txt"}, buf)
+
+		assert.Equal(t, "-r     flag --recursive\n"+
+			"a.txt  argument SRC\n"+
+			"--     terminator\n"+
+			"b.txt  argument DST\n"+
+			"c.txt  rest argument\n", buf.String())
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		var src, dst string
+		p := New()
+		p.Arg(&src, "SRC", "Source file")
+		p.Arg(&dst, "DST", "Destination file").Required()
+
+		assert.EqualError(t, p.Validate(), "required argument DST follows optional argument SRC")
+	})
+
+	t.Run("FlagModifierPanics", func(t *testing.T) {
+		var s string
+		p := New()
+		f := p.Arg(&s, "SRC", "Source file")
+
+		assert.Panics(t, func() { f.Short('s') })
+		assert.Panics(t, func() { f.Env("SRC") })
+		assert.Panics(t, func() { f.FromSecret("src") })
+		assert.Panics(t, func() { f.Hidden() })
+		assert.Panics(t, func() { f.Obsolete("gone") })
+		assert.Panics(t, func() { f.RequiredWhenEnv("MODE", "copy") })
+		assert.NotPanics(t, func() { f.TrimSpace().Default(".") })
+	})
+
+	t.Run("DuplicatePanic", func(t *testing.T) {
+		var src string
+		p := New()
+		p.Arg(&src, "SRC", "Source file")
+		assert.Panics(t, func() {
+			p.Arg(&src, "SRC", "Source file")
+		})
+	})
+}
+
+func TestParserPositionalRange(t *testing.T) {
+	newParser := func(min, max int) (*Parser, *[]string) {
+		var files []string
+		p := New(WithUnknownArgHandler(func(arg string) error {
+			files = append(files, arg)
+			return nil
+		}))
+		p.PositionalRange(min, max)
+		return p, &files
+	}
+
+	t.Run("TooFew", func(t *testing.T) {
+		p, _ := newParser(1, 3)
+		err := p.ParseArgs(nil)
+		assert.EqualError(t, err, "expected between 1 and 3 arguments, got 0")
+	})
+
+	t.Run("InRange", func(t *testing.T) {
+		p, files := newParser(1, 3)
+		err := p.ParseArgs([]string{"a", "b", "--", "c"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, *files)
+	})
+
+	t.Run("TooMany", func(t *testing.T) {
+		p, _ := newParser(1, 3)
+		err := p.ParseArgs([]string{"a", "b", "c", "d"})
+		assert.EqualError(t, err, "expected between 1 and 3 arguments, got 4")
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		p, _ := newParser(2, -1)
+		err := p.ParseArgs([]string{"a", "b", "c", "d", "e"})
+		require.NoError(t, err)
+
+		err = p.ParseArgs([]string{"a"})
+		assert.EqualError(t, err, "expected at least 2 arguments, got 1")
+	})
+
+	t.Run("DeclaredAndRest", func(t *testing.T) {
+		var (
+			src  string
+			rest []string
+		)
+
+		p := New()
+		p.Arg(&src, "SRC", "Source file")
+		p.RestArgs(&rest)
+		p.PositionalRange(1, 2)
+
+		err := p.ParseArgs([]string{"a", "--", "b", "c"})
+		assert.EqualError(t, err, "expected between 1 and 2 arguments, got 3")
+
+		err = p.ParseArgs(nil)
+		assert.EqualError(t, err, "expected between 1 and 2 arguments, got 0")
+	})
+
+	t.Run("InvalidRangePanic", func(t *testing.T) {
+		assert.Panics(t, func() { New().PositionalRange(3, 1) })
+		assert.Panics(t, func() { New().PositionalRange(-1, 1) })
+	})
+}
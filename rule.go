@@ -0,0 +1,162 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ruleFunc evaluates a rule against the set-state of flags.
+type ruleFunc func(isSet func(string) bool) bool
+
+type rule struct {
+	expr string
+	eval ruleFunc
+}
+
+// Rule registers a constraint over flags explicitly set from any source
+// other than defaults, e.g. "tls-cert AND tls-key" or "json XOR yaml".
+// Operators from the highest to the lowest precedence are NOT, AND, XOR
+// and OR; parentheses could be used for grouping.
+func (p *Parser) Rule(expr string) {
+	rp := &ruleParser{parser: p, tokens: tokenizeRule(expr)}
+
+	eval, err := rp.parseOr()
+	if err == nil && rp.pos < len(rp.tokens) {
+		err = fmt.Errorf("unexpected %q", rp.tokens[rp.pos])
+	}
+	if err != nil {
+		panic(fmt.Sprintf("invalid rule %q: %v", expr, err))
+	}
+
+	p.rules = append(p.rules, rule{expr: expr, eval: eval})
+}
+
+func (p *Parser) checkRules() []error {
+	isSet := func(name string) bool {
+		return p.flagIndex[name].getSource() > sourceDefault
+	}
+
+	var checkErrs []error
+	for _, r := range p.rules {
+		if !r.eval(isSet) {
+			checkErrs = append(checkErrs, fmt.Errorf("flags rule violated: %s", r.expr))
+		}
+	}
+
+	return checkErrs
+}
+
+func tokenizeRule(expr string) []string {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	return strings.Fields(expr)
+}
+
+type ruleParser struct {
+	parser *Parser
+	tokens []string
+	pos    int
+}
+
+func (rp *ruleParser) peek() string {
+	if rp.pos < len(rp.tokens) {
+		return rp.tokens[rp.pos]
+	}
+	return ""
+}
+
+func (rp *ruleParser) parseOr() (ruleFunc, error) {
+	return rp.parseBinary("OR", rp.parseXor, func(a, b bool) bool { return a || b })
+}
+
+func (rp *ruleParser) parseXor() (ruleFunc, error) {
+	return rp.parseBinary("XOR", rp.parseAnd, func(a, b bool) bool { return a != b })
+}
+
+func (rp *ruleParser) parseAnd() (ruleFunc, error) {
+	return rp.parseBinary("AND", rp.parseNot, func(a, b bool) bool { return a && b })
+}
+
+func (rp *ruleParser) parseBinary(op string, next func() (ruleFunc, error), combine func(a, b bool) bool) (ruleFunc, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	for rp.peek() == op {
+		rp.pos++
+
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		l := left
+		left = func(isSet func(string) bool) bool {
+			return combine(l(isSet), right(isSet))
+		}
+	}
+
+	return left, nil
+}
+
+func (rp *ruleParser) parseNot() (ruleFunc, error) {
+	if rp.peek() != "NOT" {
+		return rp.parsePrimary()
+	}
+	rp.pos++
+
+	operand, err := rp.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(isSet func(string) bool) bool {
+		return !operand(isSet)
+	}, nil
+}
+
+func (rp *ruleParser) parsePrimary() (ruleFunc, error) {
+	tok := rp.peek()
+	rp.pos++
+
+	switch tok {
+	case "":
+		return nil, errors.New("unexpected end of rule")
+	case "(":
+		inner, err := rp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if rp.peek() != ")" {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		rp.pos++
+		return inner, nil
+	case ")", "AND", "OR", "XOR", "NOT":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+
+	name := strings.TrimPrefix(tok, "--")
+	if _, ok := rp.parser.flagIndex[name]; !ok {
+		return nil, fmt.Errorf("flag with name %s is not registered", name)
+	}
+
+	return func(isSet func(string) bool) bool {
+		return isSet(name)
+	}, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserRule(t *testing.T) {
+	t.Run("XOR", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			args  []string
+			valid bool
+		}{
+			{name: "JSON", args: []string{"--json"}, valid: true},
+			{name: "YAML", args: []string{"--yaml"}, valid: true},
+			{name: "Both", args: []string{"--json", "--yaml"}, valid: false},
+			{name: "None", args: nil, valid: false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var jsonOut, yamlOut bool
+				p := New()
+				p.Bool(&jsonOut, "json", "Test flag")
+				p.Bool(&yamlOut, "yaml", "Test flag")
+				p.Rule("json XOR yaml")
+
+				err := p.ParseArgs(tt.args)
+				if !tt.valid {
+					assert.EqualError(t, err, "flags rule violated: json XOR yaml")
+					return
+				}
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("Grouping", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			args  []string
+			valid bool
+		}{
+			{name: "Both", args: []string{"--tls-cert=cert.pem", "--tls-key=key.pem"}, valid: true},
+			{name: "None", args: nil, valid: true},
+			{name: "CertOnly", args: []string{"--tls-cert=cert.pem"}, valid: false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var cert, key string
+				p := New()
+				p.String(&cert, "tls-cert", "Test flag").Default("default.pem")
+				p.String(&key, "tls-key", "Test flag")
+				p.Rule("NOT (tls-cert OR tls-key) OR (tls-cert AND tls-key)")
+
+				err := p.ParseArgs(tt.args)
+				if !tt.valid {
+					assert.EqualError(t, err, "flags rule violated: NOT (tls-cert OR tls-key) OR (tls-cert AND tls-key)")
+					return
+				}
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("EnvSet", func(t *testing.T) {
+		t.Setenv("TLS_KEY", "key.pem")
+
+		var cert, key string
+		p := New()
+		p.String(&cert, "tls-cert", "Test flag")
+		p.String(&key, "tls-key", "Test flag")
+		p.Rule("tls-cert AND tls-key")
+
+		err := p.ParseArgs([]string{"--tls-cert=cert.pem"})
+		require.NoError(t, err)
+
+		p = New()
+		p.String(&cert, "tls-cert", "Test flag")
+		p.String(&key, "other-key", "Test flag")
+		p.Rule("tls-cert AND other-key")
+
+		err = p.ParseArgs([]string{"--tls-cert=cert.pem"})
+		assert.EqualError(t, err, "flags rule violated: tls-cert AND other-key")
+	})
+
+	t.Run("InvalidPanic", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "test-flag", "Test flag")
+
+		for _, expr := range []string{
+			"",
+			"test-flag AND",
+			"(test-flag",
+			"test-flag test-flag",
+			"test-flag AND nonexistent-flag",
+		} {
+			assert.Panics(t, func() {
+				p.Rule(expr)
+			}, expr)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+type jsonSchema struct {
+	Schema     string                        `json:"$schema,omitempty"`
+	Type       string                        `json:"type"`
+	Properties map[string]jsonSchemaProperty `json:"properties"`
+	Required   []string                      `json:"required,omitempty"`
+}
+
+type jsonSchemaProperty struct {
+	Type        string              `json:"type,omitempty"`
+	Items       *jsonSchemaProperty `json:"items,omitempty"`
+	FlagType    string              `json:"x-flenv-type,omitempty"`
+	Description string              `json:"description,omitempty"`
+}
+
+func (p *Parser) WriteJSONSchema(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p.jsonSchema())
+}
+
+func (p *Parser) ConformsTo(r io.Reader) error {
+	var expected jsonSchema
+	if err := json.NewDecoder(r).Decode(&expected); err != nil {
+		return fmt.Errorf("decoding schema: %w", err)
+	}
+
+	actual := p.jsonSchema()
+
+	var errs []error
+	for _, name := range sortedKeys(expected.Properties) {
+		prop, ok := actual.Properties[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("flag --%s is missing", name))
+			continue
+		}
+
+		if want := expected.Properties[name]; !want.matchesType(prop) {
+			errs = append(errs, fmt.Errorf("flag --%s has type %s, expected %s", name, prop.FlagType, want.typeName()))
+		}
+
+		switch want, got := slices.Contains(expected.Required, name), slices.Contains(actual.Required, name); {
+		case want && !got:
+			errs = append(errs, fmt.Errorf("flag --%s is optional, expected required", name))
+		case !want && got:
+			errs = append(errs, fmt.Errorf("flag --%s is required, expected optional", name))
+		}
+	}
+
+	for _, name := range sortedKeys(actual.Properties) {
+		if _, ok := expected.Properties[name]; !ok {
+			errs = append(errs, fmt.Errorf("unexpected flag --%s", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *Parser) jsonSchema() jsonSchema {
+	schema := jsonSchema{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Type:       "object",
+		Properties: make(map[string]jsonSchemaProperty),
+	}
+
+	for _, flag := range p.sortedFlags() {
+		if p.isBuiltinFlag(flag) {
+			continue
+		}
+
+		prop := jsonSchemaProperty{
+			FlagType:    flag.getTypeName(),
+			Description: flag.getHelpMessage(),
+		}
+
+		switch flag.getTypeName() {
+		case "bool":
+			prop.Type = "boolean"
+		case "int":
+			prop.Type = "integer"
+		case "float":
+			prop.Type = "number"
+		case "strings":
+			prop.Type = "array"
+			prop.Items = &jsonSchemaProperty{Type: "string"}
+		case "pairs":
+			prop.Type = "array"
+			prop.Items = &jsonSchemaProperty{Type: "object"}
+		case "json":
+			// arbitrary JSON value, no type constraint
+		default:
+			prop.Type = "string"
+		}
+
+		schema.Properties[flag.getName()] = prop
+		if flag.isRequired() {
+			schema.Required = append(schema.Required, flag.getName())
+		}
+	}
+
+	return schema
+}
+
+// matchesType compares flenv type names if present, falling back to JSON
+// types for schemas not produced by WriteJSONSchema.
+func (prop jsonSchemaProperty) matchesType(other jsonSchemaProperty) bool {
+	if prop.FlagType != "" {
+		return prop.FlagType == other.FlagType
+	}
+	return prop.Type == other.Type
+}
+
+func (prop jsonSchemaProperty) typeName() string {
+	if prop.FlagType != "" {
+		return prop.FlagType
+	}
+	return prop.Type
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// valueSchema is the subset of JSON Schema supported for validating JSON
+// flag values, which avoids depending on a full-blown validator. Other
+// keywords are rejected rather than ignored, except for annotations.
+type valueSchema struct {
+	Schema      string `json:"$schema"`
+	ID          string `json:"$id"`
+	Comment     string `json:"$comment"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Default     any    `json:"default"`
+	Examples    []any  `json:"examples"`
+
+	Type                 any                     `json:"type"`
+	Properties           map[string]*valueSchema `json:"properties"`
+	Required             []string                `json:"required"`
+	AdditionalProperties *bool                   `json:"additionalProperties"`
+	Items                *valueSchema            `json:"items"`
+	Enum                 []any                   `json:"enum"`
+	Minimum              *float64                `json:"minimum"`
+	Maximum              *float64                `json:"maximum"`
+	MinLength            *int                    `json:"minLength"`
+	MaxLength            *int                    `json:"maxLength"`
+}
+
+func (s *valueSchema) validate(path string, v any) error {
+	if s.Type != nil {
+		if err := s.validateType(path, v); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Enum) != 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return fmt.Errorf("%s: value is not one of the allowed values", path)
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+
+		for _, name := range sortedKeys(v) {
+			prop, ok := s.Properties[name]
+			switch {
+			case ok:
+				if err := prop.validate(path+"."+name, v[name]); err != nil {
+					return err
+				}
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s: must be at least %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s: must be at most %v", path, *s.Maximum)
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s: must be at least %d characters", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s: must be at most %d characters", path, *s.MaxLength)
+		}
+	}
+
+	return nil
+}
+
+func (s *valueSchema) validateType(path string, v any) error {
+	var types []string
+	switch t := s.Type.(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, t := range t {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+	}
+
+	actual := jsonTypeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), actual)
+}
+
+func jsonTypeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
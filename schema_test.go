@@ -0,0 +1,198 @@
+// Copyright 2025 Sergey Vinogradov
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flenv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserWriteJSONSchema(t *testing.T) {
+	var (
+		d time.Duration
+		i int
+	)
+
+	p := New()
+	p.Duration(&d, "timeout", "Request timeout").Default(30 * time.Second)
+	p.Int(&i, "port", "Port to listen on").Required()
+
+	buf := bytes.NewBuffer(nil)
+	err := p.WriteJSONSchema(buf)
+	require.NoError(t, err)
+
+	const expected = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "port": {
+      "type": "integer",
+      "x-flenv-type": "int",
+      "description": "Port to listen on"
+    },
+    "timeout": {
+      "type": "string",
+      "x-flenv-type": "duration",
+      "description": "Request timeout"
+    }
+  },
+  "required": [
+    "port"
+  ]
+}
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestParserConformsTo(t *testing.T) {
+	newParser := func() *Parser {
+		var (
+			d time.Duration
+			i int
+		)
+
+		p := New()
+		p.Duration(&d, "timeout", "Request timeout").Default(30 * time.Second)
+		p.Int(&i, "port", "Port to listen on").Required()
+		return p
+	}
+
+	t.Run("Conforming", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		err := newParser().WriteJSONSchema(buf)
+		require.NoError(t, err)
+
+		err = newParser().ConformsTo(buf)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Drift", func(t *testing.T) {
+		var (
+			d time.Duration
+			s string
+		)
+
+		buf := bytes.NewBuffer(nil)
+		err := newParser().WriteJSONSchema(buf)
+		require.NoError(t, err)
+
+		p := New()
+		p.Duration(&d, "timeout", "Request timeout").Required()
+		p.String(&s, "host", "Host to listen on")
+
+		err = p.ConformsTo(buf)
+		assert.EqualError(t, err, "flag --port is missing\n"+
+			"flag --timeout is required, expected optional\n"+
+			"unexpected flag --host")
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		var s string
+		p := New()
+		p.String(&s, "port", "Port to listen on")
+
+		err := p.ConformsTo(strings.NewReader(`{"type":"object","properties":{"port":{"type":"integer"}}}`))
+		assert.EqualError(t, err, "flag --port has type string, expected integer")
+	})
+}
+
+func TestFlagJSONSchema(t *testing.T) {
+	type opts struct {
+		Retries int    `json:"retries"`
+		Timeout string `json:"timeout"`
+	}
+
+	const schema = `{
+		"type": "object",
+		"properties": {
+			"retries": {"type": "integer", "minimum": 0, "maximum": 10},
+			"timeout": {"type": "string", "minLength": 2}
+		},
+		"required": ["retries"],
+		"additionalProperties": false
+	}`
+
+	t.Run("NonJSONPanic", func(t *testing.T) {
+		var s string
+		f := NewStringFlag(&s, "test-flag", "Test flag")
+		assert.Panics(t, func() {
+			f.JSONSchema(schema)
+		})
+	})
+
+	t.Run("InvalidSchemaPanic", func(t *testing.T) {
+		var v opts
+		f := NewJSONFlag(&v, "opts", "Test flag")
+		assert.Panics(t, func() {
+			f.JSONSchema(`{"type": `)
+		})
+	})
+
+	t.Run("UnsupportedKeywordPanic", func(t *testing.T) {
+		for keyword, schema := range map[string]string{
+			"pattern": `{"type": "string", "pattern": "^[a-z]+$"}`,
+			"oneOf":   `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`,
+			"$ref":    `{"type": "object", "properties": {"name": {"$ref": "#/$defs/name"}}}`,
+			"format":  `{"type": "array", "items": {"type": "string", "format": "email"}}`,
+		} {
+			var v any
+			f := NewJSONFlag(&v, "opts", "Test flag")
+			assert.PanicsWithValue(t, `invalid JSON schema for --opts: json: unknown field "`+keyword+`"`, func() {
+				f.JSONSchema(schema)
+			})
+		}
+	})
+
+	t.Run("Annotations", func(t *testing.T) {
+		var v opts
+		f := NewJSONFlag(&v, "opts", "Test flag")
+		assert.NotPanics(t, func() {
+			f.JSONSchema(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Options", "type": "object", "properties": {"retries": {"type": "integer", "description": "Retries", "default": 3}}}`)
+		})
+	})
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: `{"retries": 3, "timeout": "5s"}`},
+		{value: `{"retries": "3"}`, expected: "invalid value for --opts: $.retries: expected integer, got string"},
+		{value: `{"retries": 1.5}`, expected: "invalid value for --opts: $.retries: expected integer, got number"},
+		{value: `{"retries": 30}`, expected: "invalid value for --opts: $.retries: must be at most 10"},
+		{value: `{"timeout": "5s"}`, expected: `invalid value for --opts: $: missing required property "retries"`},
+		{value: `{"retries": 3, "extra": true}`, expected: `invalid value for --opts: $: unexpected property "extra"`},
+		{value: `[]`, expected: "invalid value for --opts: $: expected object, got array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v opts
+			f := NewJSONFlag(&v, "opts", "Test flag").JSONSchema(schema)
+			err := f.setValueFromString(tt.value)
+			if tt.expected != "" {
+				assert.EqualError(t, err, tt.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, opts{Retries: 3, Timeout: "5s"}, v)
+		})
+	}
+}